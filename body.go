@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxBodySize the largest request body hashed into a cache key by default
+const defaultMaxBodySize = 1 << 20
+
+// errBodyTooLarge means the request body is larger than the configured maximum
+var errBodyTooLarge = errors.New("cache: request body too large")
+
+// readCloser puts the bytes already read back in front of the remaining body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// hashRequestBody returns the hex SHA-256 of the request body, reading at most maxSize bytes.
+// The body is restored for the handler, errBodyTooLarge is returned if it has more than maxSize bytes.
+// JSON bodies are canonicalized first when canonicalJSON is set.
+func hashRequestBody(req *http.Request, maxSize int64, canonicalJSON bool) (string, error) {
+	body, err := readRequestBody(req, maxSize)
+	if err != nil {
+		return "", err
+	}
+
+	if canonicalJSON && isJSONContentType(req.Header.Get("Content-Type")) {
+		if canonical, err := canonicalizeJSON(body); err == nil {
+			body = canonical
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readRequestBody reads at most maxSize bytes of the request body and restores it for the handler,
+// errBodyTooLarge is returned if it has more than maxSize bytes
+func readRequestBody(req *http.Request, maxSize int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil || int64(len(body)) > maxSize {
+		req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+		if err == nil {
+			err = errBodyTooLarge
+		}
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// canonicalizeJSON re-encodes a JSON document with sorted object keys and without whitespace,
+// numbers are kept as written
+func canonicalizeJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("cache: trailing data after json document")
+	}
+	return json.Marshal(v)
+}
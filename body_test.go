@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockPostRequest(middleware gin.HandlerFunc, body string, contentType string) (*httptest.ResponseRecorder, string) {
+	var handlerBody string
+	testWriter := httptest.NewRecorder()
+
+	_, engine := gin.CreateTestContext(testWriter)
+	engine.Use(middleware)
+	engine.POST("/cache", func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		handlerBody = string(data)
+		c.String(http.StatusOK, fmt.Sprintf("rand:%d", rand.Int()))
+	})
+
+	testRequest := httptest.NewRequest(http.MethodPost, "/cache", strings.NewReader(body))
+	testRequest.Header.Set("Content-Type", contentType)
+	engine.ServeHTTP(testWriter, testRequest)
+
+	return testWriter, handlerBody
+}
+
+func TestHashRequestBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/cache", strings.NewReader(`{"a":1}`))
+	hash, err := hashRequestBody(req, 16, false)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+	data, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"a":1}`, string(data))
+
+	// a larger body is restored whole
+	req = httptest.NewRequest(http.MethodPost, "/cache", strings.NewReader(strings.Repeat("x", 32)))
+	_, err = hashRequestBody(req, 16, false)
+	assert.Equal(t, errBodyTooLarge, err)
+	data, _ = io.ReadAll(req.Body)
+	assert.Equal(t, strings.Repeat("x", 32), string(data))
+}
+
+func TestCanonicalizeJSON(t *testing.T) {
+	canonical, err := canonicalizeJSON([]byte(" {\"b\": [1, 2.50, {\"d\":null, \"c\":true}],\n \"a\": \"x\"} "))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"x","b":[1,2.50,{"c":true,"d":null}]}`, string(canonical))
+
+	_, err = canonicalizeJSON([]byte(`{"a":1} {"b":2}`))
+	assert.Error(t, err)
+	_, err = canonicalizeJSON([]byte(`{"a":`))
+	assert.Error(t, err)
+}
+
+func TestCacheByRequestURIPostBody(t *testing.T) {
+	cacheURIMiddleware := CacheByRequestURI(newRedisStore(1*time.Minute), 3*time.Second,
+		WithMaxBodySize(64), WithPrefixKey("post_body:"))
+
+	w1, body := mockPostRequest(cacheURIMiddleware, `{"a":1,"b":2}`, "application/json")
+	assert.Equal(t, `{"a":1,"b":2}`, body)
+	w2, _ := mockPostRequest(cacheURIMiddleware, `{"a":1,"b":2}`, "application/json")
+	w3, _ := mockPostRequest(cacheURIMiddleware, `{"b":2,"a":1}`, "application/json")
+	assert.Equal(t, w1.Body, w2.Body)
+	assert.NotEqual(t, w1.Body, w3.Body)
+
+	// bodies over the maximum size are passed to the handler and not cached
+	large := `{"a":"` + strings.Repeat("x", 64) + `"}`
+	w4, body := mockPostRequest(cacheURIMiddleware, large, "application/json")
+	assert.Equal(t, large, body)
+	w5, _ := mockPostRequest(cacheURIMiddleware, large, "application/json")
+	assert.NotEqual(t, w4.Body, w5.Body)
+}
+
+func TestCacheByRequestURICanonicalJSONBody(t *testing.T) {
+	cacheURIMiddleware := CacheByRequestURI(newRedisStore(1*time.Minute), 3*time.Second,
+		WithCanonicalJSONBody(), WithPrefixKey("canonical_json_body:"))
+
+	w1, body := mockPostRequest(cacheURIMiddleware, "{\n  \"b\": 2,\n  \"a\": 1\n}", "application/json; charset=utf-8")
+	assert.Equal(t, "{\n  \"b\": 2,\n  \"a\": 1\n}", body)
+	w2, _ := mockPostRequest(cacheURIMiddleware, `{"a":1,"b":2}`, "application/json")
+	assert.Equal(t, w1.Body, w2.Body)
+
+	// other content types are hashed as is
+	w3, _ := mockPostRequest(cacheURIMiddleware, `{"b":2,"a":1}`, "text/plain")
+	assert.NotEqual(t, w1.Body, w3.Body)
+}
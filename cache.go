@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
-	"io"
+	"errors"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/cache/persistence"
@@ -16,6 +21,19 @@ import (
 	"golang.org/x/sync/singleflight"
 )
 
+// defaultBackendTimeout bounds the wait for the backend on a cache miss, see WithBackendTimeout
+const defaultBackendTimeout = 40 * time.Second
+
+// the states of the handler of a request loading an entry
+const (
+	handlerPending int32 = iota
+	handlerRunning
+	handlerAbandoned
+)
+
+// errBackendAbandoned means the request timed out before its load started
+var errBackendAbandoned = errors.New("cache: backend abandoned after timeout")
+
 // Strategy the cache strategy
 type Strategy struct {
 	CacheKey string
@@ -25,6 +43,14 @@ type Strategy struct {
 
 	// CacheDuration
 	CacheDuration time.Duration
+
+	// StaleCacheDuration if greater than CacheDuration, the entry is kept until StaleCacheDuration and
+	// served stale while being revalidated in background, see WithStaleWhileRevalidate
+	StaleCacheDuration time.Duration
+
+	// Namespace if not empty, the cache key is built under this namespace so that
+	// all of its entries can be invalidated at once with persistence.Namespace.Invalidate
+	Namespace string
 }
 
 // GetCacheStrategyByRequest User can this function to design custom cache strategy by request.
@@ -39,24 +65,35 @@ func Cache(
 	opts ...Option,
 ) gin.HandlerFunc {
 	cfg := newConfigByOpts(opts...)
-	return cache(defaultCacheStore, defaultExpire, cfg)
+	return cache(defaultCacheStore, defaultExpire, cfg, nil)
 }
 
+// cache returns the middleware, or the wrapper of handle when it isn't nil
 func cache(
 	defaultCacheStore persistence.CacheStore,
 	defaultExpire time.Duration,
 	cfg *Config,
+	handle gin.HandlerFunc,
 ) gin.HandlerFunc {
 	if cfg.getCacheStrategyByRequest == nil {
 		panic("cache strategy is nil")
 	}
 
+	next := func(c *gin.Context) {
+		c.Next()
+	}
+	if handle != nil {
+		next = handle
+	}
+
 	sfGroup := singleflight.Group{}
+	// cache keys being revalidated in background
+	revalidating := sync.Map{}
 
 	return func(c *gin.Context) {
 		shouldCache, cacheStrategy := cfg.getCacheStrategyByRequest(c)
 		if !shouldCache {
-			c.Next()
+			next(c)
 			return
 		}
 
@@ -72,34 +109,117 @@ func cache(
 			cacheStore = cacheStrategy.CacheStore
 		}
 
+		if cacheStrategy.Namespace != "" {
+			namespacedKey, err := persistence.NewNamespace(cacheStore, cacheStrategy.Namespace).Key(context.TODO(), cacheKey)
+			if err != nil {
+				cfg.logger.Errorf("get namespace %s error: %s", cacheStrategy.Namespace, err)
+				next(c)
+				return
+			}
+			cacheKey = namespacedKey
+		}
+
+		// baseKey the key before selecting the variant of the request
+		baseKey := cacheKey
+		if cfg.vary {
+			cacheKey = lookupVariantKey(context.TODO(), cacheStore, baseKey, c.Request.Header)
+		}
+
 		cacheDuration := defaultExpire
 		if cacheStrategy.CacheDuration > 0 {
 			cacheDuration = cacheStrategy.CacheDuration
 		}
 
-		// read cache first
-		{
+		if cfg.expireJitter > 0 {
+			cacheDuration += time.Duration(rand.Int63n(int64(cfg.expireJitter)))
+		}
+
+		// storeDuration how long an entry fresh for cacheDuration is kept to be served stale
+		storeDuration := func(cacheDuration time.Duration) time.Duration {
+			storeDuration := cacheDuration
+			if cfg.revalidateHandler != nil {
+				staleDuration := cfg.staleExpire
+				if cacheStrategy.StaleCacheDuration > 0 {
+					staleDuration = cacheStrategy.StaleCacheDuration
+				}
+				if staleDuration > storeDuration {
+					storeDuration = staleDuration
+				}
+			}
+			if cfg.staleIfErrorExpire > 0 && cacheDuration+cfg.staleIfErrorExpire > storeDuration {
+				storeDuration = cacheDuration + cfg.staleIfErrorExpire
+			}
+			return storeDuration
+		}
+
+		var reqCacheControl requestCacheControl
+		if cfg.cacheControl {
+			reqCacheControl = parseRequestCacheControl(c.Request.Header)
+		}
+
+		// staleCache is served if the backend fails
+		var staleCache *ResponseCache
+
+		// read cache first, unless this is the background revalidation of a stale entry
+		// or the client asks to skip it
+		if !isRevalidateRequest(c.Request) && !reqCacheControl.noCache {
 			respCache := &ResponseCache{}
 			err := cacheStore.Get(context.TODO(), cacheKey, &respCache)
 			if err == nil {
-				replyWithCache(c, cfg, respCache)
-				cfg.hitCacheCallback(c)
-				return
+				now := time.Now()
+				// the max-age and min-fresh of the request may refuse the stored response
+				acceptable := reqCacheControl.acceptsAge(respCache, now)
+				switch {
+				case acceptable && respCache.isFresh(now) &&
+					(reqCacheControl.onlyIfCached || !respCache.shouldRefreshEarly(now, cfg.earlyExpirationBeta)):
+					writeCacheStatus(c, cfg, CacheStatusHit, respCache, cacheKey)
+					replyWithCache(c, cfg, respCache)
+					cfg.hitCacheCallback(c)
+					return
+				case respCache.isFresh(now):
+					// refreshed early, the entry is still good enough if the backend fails
+					if cfg.staleIfErrorExpire > 0 {
+						staleCache = respCache
+					}
+				case acceptable && cfg.revalidateHandler != nil:
+					revalidateInBackground(c, cfg, &revalidating, cacheKey)
+					writeCacheStatus(c, cfg, CacheStatusStale, respCache, cacheKey)
+					replyWithCache(c, cfg, respCache)
+					cfg.hitCacheCallback(c)
+					return
+				case acceptable && reqCacheControl.acceptsStale(respCache, now):
+					writeCacheStatus(c, cfg, CacheStatusStale, respCache, cacheKey)
+					replyWithCache(c, cfg, respCache)
+					cfg.hitCacheCallback(c)
+					return
+				case cfg.staleIfErrorExpire > 0 && now.Sub(respCache.FreshUntil) < cfg.staleIfErrorExpire:
+					staleCache = respCache
+				}
 			}
 
 			// if err != persistence.ErrCacheMiss {
 			// }
 		}
 
+		if reqCacheControl.onlyIfCached {
+			c.AbortWithStatus(http.StatusGatewayTimeout)
+			return
+		}
+
 		// cache miss, then call the backend
 
-		// use responseCacheWriter in order to record the response
-		cacheWriter := &responseCacheWriter{ResponseWriter: c.Writer}
+		writeCacheStatus(c, cfg, CacheStatusMiss, nil, cacheKey)
+
+		// use responseCacheWriter in order to record the response,
+		// hold it back while it may still be replaced by the stale one or get its ETag
+		cacheWriter := newResponseCacheWriter(c.Writer, staleCache != nil || cfg.etag)
 		c.Writer = cacheWriter
 
 		inFlight := false
+		// handlerState whether the handler runs on c, set by the load or by the timeout, whichever comes first
+		var handlerState int32
 
-		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Second*40)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.backendTimeout)
 		defer cancel()
 		rawRespCacheCh := sfGroup.DoChan(cacheKey, func() (interface{}, error) {
 			// if cfg.singleFlightForgetTimeout > 0 {
@@ -109,16 +229,64 @@ func cache(
 			// 	defer forgetTimer.Stop()
 			// }
 
-			c.Next()
+			if !atomic.CompareAndSwapInt32(&handlerState, handlerPending, handlerRunning) {
+				// the request timed out before the load started, c is no longer usable
+				return nil, errBackendAbandoned
+			}
+
+			start := time.Now()
+			next(c)
 
 			inFlight = true
 
 			respCache := &ResponseCache{}
 			respCache.fillWithCacheWriter(cacheWriter)
+			respCache.Header = cfg.headerFilter.filter(respCache.Header)
+			stripDiagnosticHeaders(respCache.Header)
+			respCache.StoredAt = time.Now()
+			respCache.ComputeDuration = time.Since(start)
+			if cfg.etag {
+				respCache.fillETag()
+				// the response sent now carries the ETag of the stored one
+				liveHeader := cacheWriter.Header()
+				if etag := respCache.Header.Get("ETag"); etag != "" && liveHeader.Get("ETag") == "" && liveHeader.Get("Last-Modified") == "" {
+					liveHeader.Set("ETag", etag)
+				}
+			}
 
 			// only cache 2xx response
-			if !c.IsAborted() && cacheWriter.Status() < 300 && cacheWriter.Status() >= 200 {
-				_ = cacheStore.Set(context.TODO(), cacheKey, respCache, cacheDuration)
+			shouldStore := !c.IsAborted() && cacheWriter.Status() < 300 && cacheWriter.Status() >= 200
+			freshDuration := cacheDuration
+			if shouldStore && cfg.cacheControl {
+				freshDuration, shouldStore = responseCacheTTL(c.Request, respCache.Header, cacheDuration, time.Now())
+				shouldStore = shouldStore && !reqCacheControl.noStore
+			}
+			respCache.FreshUntil = time.Now().Add(freshDuration)
+
+			if shouldStore && cfg.contentEncoding != "" {
+				if err := respCache.encodeBody(cfg.contentEncoding); err != nil {
+					cfg.logger.Errorf("encode response error: %s", err)
+					shouldStore = false
+				}
+			}
+
+			storeKey := cacheKey
+			if cfg.vary {
+				varyNames, varyWildcard := responseVary(cfg, respCache.Header)
+				respCache.variant = variantKey(baseKey, varyNames, c.Request.Header)
+				storeKey = respCache.variant
+				shouldStore = shouldStore && !varyWildcard
+				if shouldStore {
+					if len(varyNames) > 0 {
+						_ = cacheStore.Set(context.TODO(), varyIndexKey(baseKey), varyNames, storeDuration(freshDuration))
+					} else {
+						_ = cacheStore.Delete(context.TODO(), varyIndexKey(baseKey))
+					}
+				}
+			}
+
+			if shouldStore {
+				_ = cacheStore.Set(context.TODO(), storeKey, respCache, storeDuration(freshDuration))
 			}
 			return respCache, nil
 		})
@@ -126,18 +294,57 @@ func cache(
 		select {
 		case <-ctx.Done():
 			sfGroup.Forget(cacheKey)
-			c.AbortWithStatus(500)
+			if atomic.CompareAndSwapInt32(&handlerState, handlerPending, handlerAbandoned) {
+				// the handler doesn't run on c, the request joined the load of another one
+				// or its own never started
+				c.Writer = cacheWriter.ResponseWriter
+				if staleCache != nil {
+					replyWithStaleCache(c, cfg, staleCache, cacheKey)
+					return
+				}
+				c.AbortWithStatus(500)
+				return
+			}
+			// the handler runs on c, the reply is sent aside unless the handler already started
+			// its own, and c is only released once the handler is done as gin reuses it
+			if cacheWriter.detach() {
+				replyAfterTimeout(c, cfg, cacheWriter, staleCache, cacheKey)
+			}
+			<-rawRespCacheCh
 			return
 		case ret := <-rawRespCacheCh:
 			if ret.Err != nil {
 				sfGroup.Forget(cacheKey)
+				if staleCache != nil {
+					c.Writer = cacheWriter.ResponseWriter
+					replyWithStaleCache(c, cfg, staleCache, cacheKey)
+					return
+				}
 				c.AbortWithStatus(500)
 				return
 			}
+			respCache := ret.Val.(*ResponseCache)
+			if staleCache != nil && respCache.Status >= http.StatusInternalServerError {
+				c.Writer = cacheWriter.ResponseWriter
+				replyWithStaleCache(c, cfg, staleCache, cacheKey)
+				return
+			}
 			if !inFlight {
-				replyWithCache(c, cfg, ret.Val.(*ResponseCache))
+				c.Writer = cacheWriter.ResponseWriter
+				if cfg.vary {
+					// the shared response may have been selected by different request headers
+					varyNames, varyWildcard := responseVary(cfg, respCache.Header)
+					if varyWildcard || variantKey(baseKey, varyNames, c.Request.Header) != respCache.variant {
+						next(c)
+						return
+					}
+				}
+				writeCacheStatus(c, cfg, CacheStatusShared, respCache, cacheKey)
+				replyWithCache(c, cfg, respCache)
 				cfg.shareSingleFlightCallback(c)
+				return
 			}
+			cacheWriter.flush()
 		}
 	}
 }
@@ -145,19 +352,29 @@ func cache(
 // CacheByRequestURI a shortcut function for caching response by uri
 func CacheByRequestURI(defaultCacheStore persistence.CacheStore, defaultExpire time.Duration, opts ...Option) gin.HandlerFunc {
 	cfg := newConfigByOpts(opts...)
+	cfg.getCacheStrategyByRequest = requestURIStrategy(cfg)
 
-	cacheStrategy := func(c *gin.Context) (bool, Strategy) {
+	return cache(defaultCacheStore, defaultExpire, cfg, nil)
+}
 
-		var suffix string
+// requestURIStrategy caches requests by their uri, the POST body and the headers of WithHeaders
+func requestURIStrategy(cfg *Config) GetCacheStrategyByRequest {
+	return func(c *gin.Context) (bool, Strategy) {
+		var bodyHash string
 		if c.Request.Method == "POST" {
-			var bodyBytes []byte
-			if c.Request.Body != nil {
-				bodyBytes, _ = io.ReadAll(c.Request.Body)
-				c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+			var err error
+			bodyHash, err = hashRequestBody(c.Request, cfg.maxBodySize, cfg.canonicalJSONBody)
+			if err != nil {
+				// too large or unreadable bodies are not cached
+				return false, Strategy{}
+			}
+		}
+		requestURI := c.Request.RequestURI
+		if cfg.urlCanonicalizer != nil {
+			if canonicalURI, err := cfg.urlCanonicalizer.Canonicalize(requestURI); err == nil {
+				requestURI = canonicalURI
 			}
-			suffix = string(bodyBytes)
 		}
-		requestURI := c.Request.RequestURI + suffix
 
 		for _, key := range cfg.headers {
 			if h, ok := c.Request.Header[key]; ok && len(h) > 0 {
@@ -169,17 +386,18 @@ func CacheByRequestURI(defaultCacheStore persistence.CacheStore, defaultExpire t
 			}
 		}
 
-		newUri, err := getRequestUriIgnoreQueryOrder(requestURI)
-		if err != nil {
-			newUri = requestURI
+		newUri := requestURI
+		if cfg.urlCanonicalizer == nil {
+			if sortedUri, err := getRequestUriIgnoreQueryOrder(requestURI); err == nil {
+				newUri = sortedUri
+			}
+		}
+		if bodyHash != "" {
+			newUri += "#body:" + bodyHash
 		}
 
 		return true, Strategy{CacheKey: newUri}
 	}
-
-	cfg.getCacheStrategyByRequest = cacheStrategy
-
-	return cache(defaultCacheStore, defaultExpire, cfg)
 }
 
 func getRequestUriIgnoreQueryOrder(requestURI string) (string, error) {
@@ -219,6 +437,33 @@ type ResponseCache struct {
 	Status int
 	Header http.Header
 	Data   []byte
+
+	// StoredAt when the response was stored
+	StoredAt time.Time
+
+	// FreshUntil the entry is stale afterwards, zero means it is fresh as long as it is stored
+	FreshUntil time.Time
+
+	// ComputeDuration how long the backend took to build the response
+	ComputeDuration time.Duration
+
+	// variant the key of the variant selected by the Vary header of the response
+	variant string
+}
+
+func (c *ResponseCache) isFresh(now time.Time) bool {
+	return c.FreshUntil.IsZero() || now.Before(c.FreshUntil)
+}
+
+// shouldRefreshEarly implements the XFetch probabilistic early expiration: the closer the entry is
+// to its expiry, and the longer it took to compute, the more likely it is to be refreshed now.
+func (c *ResponseCache) shouldRefreshEarly(now time.Time, beta float64) bool {
+	if beta <= 0 || c.FreshUntil.IsZero() || c.ComputeDuration <= 0 {
+		return false
+	}
+	// 1-Float64 is in (0, 1], so the log is finite, the gap is compared as a float as it may overflow a Duration
+	gap := -float64(c.ComputeDuration) * beta * math.Log(1-rand.Float64())
+	return gap >= float64(c.FreshUntil.Sub(now))
 }
 
 func (c *ResponseCache) fillWithCacheWriter(cacheWriter *responseCacheWriter) {
@@ -227,22 +472,200 @@ func (c *ResponseCache) fillWithCacheWriter(cacheWriter *responseCacheWriter) {
 	c.Header = cacheWriter.Header().Clone()
 }
 
-// responseCacheWriter
+// responseCacheWriter records the response written by the handler
 type responseCacheWriter struct {
 	gin.ResponseWriter
 	body bytes.Buffer
+
+	// mu guards the writer against detach, called while the handler may still be writing
+	mu sync.Mutex
+
+	// header and status are the ones of the handler, they are copied to the live writer when the
+	// response is sent, which keeps the headers it had before the handler ran until then
+	header  http.Header
+	status  int
+	written bool
+	// sent the header has been sent through the live writer
+	sent bool
+
+	// buffered the response is only recorded, and sent by flush
+	buffered bool
+}
+
+func newResponseCacheWriter(w gin.ResponseWriter, buffered bool) *responseCacheWriter {
+	return &responseCacheWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		status:         w.Status(),
+		buffered:       buffered,
+	}
+}
+
+func (w *responseCacheWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseCacheWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *responseCacheWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *responseCacheWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *responseCacheWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
 }
 
 func (w *responseCacheWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.body.Write(b)
+	w.written = true
+	if w.buffered {
+		return len(b), nil
+	}
+	w.sendHeaderLocked()
 	return w.ResponseWriter.Write(b)
 }
 
 func (w *responseCacheWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.body.WriteString(s)
+	w.written = true
+	if w.buffered {
+		return len(s), nil
+	}
+	w.sendHeaderLocked()
 	return w.ResponseWriter.WriteString(s)
 }
 
+func (w *responseCacheWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+	if !w.buffered {
+		w.sendHeaderLocked()
+	}
+}
+
+func (w *responseCacheWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.buffered {
+		w.sendHeaderLocked()
+		w.ResponseWriter.Flush()
+	}
+}
+
+// sendHeaderLocked sends the header and status of the handler through the live writer, once
+func (w *responseCacheWriter) sendHeaderLocked() {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	header := w.ResponseWriter.Header()
+	for key := range header {
+		if _, ok := w.header[key]; !ok {
+			delete(header, key)
+		}
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// flush sends what the handler left unsent once it is done: the recorded response of a buffered writer,
+// or the header of a handler that wrote no body
+func (w *responseCacheWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.buffered {
+		w.sendHeaderLocked()
+		return
+	}
+	w.buffered = false
+	if w.body.Len() > 0 && w.header.Get("Content-Length") == "" {
+		w.header.Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	w.sendHeaderLocked()
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// detach stops the handler from sending its response, which is only recorded from then on.
+// It returns false if the handler already started to send it.
+func (w *responseCacheWriter) detach() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sent {
+		return false
+	}
+	w.buffered = true
+	return true
+}
+
+// replyWithStaleCache replies with the stale entry in place of the failed response. The response of the
+// handler was buffered, so the live writer only carries the headers set before it ran.
+func replyWithStaleCache(
+	c *gin.Context,
+	cfg *Config,
+	respCache *ResponseCache,
+	cacheKey string,
+) {
+	c.Writer.Header().Set("Warning", `111 - "Revalidation Failed"`)
+	c.Writer.Header().Set("X-Cache", CacheStatusStale)
+	writeCacheStatus(c, cfg, CacheStatusStale, respCache, cacheKey)
+	replyWithCache(c, cfg, respCache)
+}
+
+// replyAfterTimeout replies with the stale entry, or a 500 without one, while the handler still runs on c.
+// The writer of the handler must be detached. Neither c nor that writer are touched: the reply is built on
+// a context of its own, then sent through the live writer and flushed so that the client doesn't wait
+// for the handler.
+func replyAfterTimeout(
+	c *gin.Context,
+	cfg *Config,
+	cacheWriter *responseCacheWriter,
+	staleCache *ResponseCache,
+	cacheKey string,
+) {
+	replyWriter := newResponseCacheWriter(cacheWriter.ResponseWriter, true)
+	replyContext := &gin.Context{Request: c.Request, Writer: replyWriter}
+	if staleCache != nil {
+		replyWithStaleCache(replyContext, cfg, staleCache, cacheKey)
+	} else {
+		replyContext.AbortWithStatus(http.StatusInternalServerError)
+	}
+	// the length tells the client where the reply ends, as the connection is held until the handler is done
+	status := replyWriter.Status()
+	if status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified &&
+		replyWriter.Header().Get("Content-Length") == "" {
+		replyWriter.Header().Set("Content-Length", strconv.Itoa(replyWriter.body.Len()))
+	}
+	replyWriter.flush()
+	replyWriter.ResponseWriter.Flush()
+}
+
 func replyWithCache(
 	c *gin.Context,
 	cfg *Config,
@@ -250,16 +673,44 @@ func replyWithCache(
 ) {
 	cfg.beforeReplyWithCacheCallback(c, respCache)
 
-	c.Writer.WriteHeader(respCache.Status)
+	if cfg.contentEncoding != "" {
+		negotiated, err := respCache.negotiateEncoding(c.Request.Header.Values("Accept-Encoding"))
+		if err != nil {
+			cfg.logger.Errorf("decode response error: %s", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		respCache = negotiated
+	}
 
-	for key, values := range respCache.Header {
-		for _, val := range values {
-			c.Writer.Header().Set(key, val)
+	// the preconditions are evaluated against the ETag of the negotiated encoding
+	if cfg.etag && isNotModified(c.Request, respCache) {
+		replyNotModified(c, respCache)
+		return
+	}
+
+	if cfg.ranges {
+		c.Writer.Header().Set("Accept-Ranges", "bytes")
+		if replyWithRange(c, cfg, respCache) {
+			return
 		}
 	}
 
+	writeCachedHeader(c, cfg, respCache)
+
+	c.Writer.WriteHeader(respCache.Status)
+
 	if _, err := c.Writer.Write(respCache.Data); err != nil {
 		cfg.logger.Errorf("write response error: %s", err)
 	}
 	c.Abort()
 }
+
+// writeCachedHeader replays the headers of the cached response, every value of a header
+// replaces the ones already set for it
+func writeCachedHeader(c *gin.Context, cfg *Config, respCache *ResponseCache) {
+	header := c.Writer.Header()
+	for key, values := range cfg.headerFilter.filter(respCache.Header) {
+		header[key] = values
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheControl the directives of Cache-Control headers, names are lower cased
+type cacheControl map[string]string
+
+func parseCacheControl(values []string) cacheControl {
+	cc := cacheControl{}
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			name, arg := directive, ""
+			if i := strings.IndexByte(directive, '='); i >= 0 {
+				name, arg = directive[:i], strings.Trim(strings.TrimSpace(directive[i+1:]), `"`)
+			}
+			cc[strings.ToLower(strings.TrimSpace(name))] = arg
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds argument of the directive
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	arg, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || seconds < 0 {
+		// RFC 9111 says an invalid delta-seconds must be treated as stale
+		return 0, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func pragmaNoCache(header http.Header) bool {
+	for _, value := range header.Values("Pragma") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestCacheControl what a request allows the cache to do
+type requestCacheControl struct {
+	// noCache the stored response must not be used without going to the backend
+	noCache bool
+	// noStore the response must not be stored
+	noStore bool
+	// onlyIfCached the backend must not be called
+	onlyIfCached bool
+
+	// maxAge the oldest stored response accepted, when hasMaxAge
+	maxAge    time.Duration
+	hasMaxAge bool
+	// minFresh how long the stored response must stay fresh at least
+	minFresh time.Duration
+	// maxStale how long past its freshness a stored response is still accepted, when hasMaxStale
+	maxStale    time.Duration
+	hasMaxStale bool
+}
+
+func parseRequestCacheControl(header http.Header) requestCacheControl {
+	cc := parseCacheControl(header.Values("Cache-Control"))
+	maxAge, hasMaxAge := cc.seconds("max-age")
+	minFresh, _ := cc.seconds("min-fresh")
+	rc := requestCacheControl{
+		// Pragma is only considered when Cache-Control is absent
+		noCache:      cc.has("no-cache") || (hasMaxAge && maxAge == 0) || (len(cc) == 0 && pragmaNoCache(header)),
+		noStore:      cc.has("no-store"),
+		onlyIfCached: cc.has("only-if-cached"),
+		maxAge:       maxAge,
+		hasMaxAge:    hasMaxAge,
+		minFresh:     minFresh,
+	}
+	if arg, ok := cc["max-stale"]; ok {
+		rc.hasMaxStale = true
+		// without a value, a response is accepted however stale it is
+		rc.maxStale = time.Duration(math.MaxInt64)
+		if arg != "" {
+			rc.maxStale, _ = cc.seconds("max-stale")
+		}
+	}
+	return rc
+}
+
+// acceptsAge reports whether the stored response is recent enough for the max-age and min-fresh of the request
+func (rc requestCacheControl) acceptsAge(respCache *ResponseCache, now time.Time) bool {
+	if rc.hasMaxAge && now.Sub(respCache.StoredAt) > rc.maxAge {
+		return false
+	}
+	return rc.minFresh <= 0 || respCache.FreshUntil.IsZero() || respCache.FreshUntil.Sub(now) >= rc.minFresh
+}
+
+// acceptsStale reports whether the stale response is within the max-stale of the request
+func (rc requestCacheControl) acceptsStale(respCache *ResponseCache, now time.Time) bool {
+	return rc.hasMaxStale && !respCache.isFresh(now) && now.Sub(respCache.FreshUntil) <= rc.maxStale
+}
+
+// responseCacheTTL returns how long the response to req may be stored according to its headers,
+// defaultTTL is used when the response says nothing about it.
+func responseCacheTTL(req *http.Request, header http.Header, defaultTTL time.Duration, now time.Time) (time.Duration, bool) {
+	cc := parseCacheControl(header.Values("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") || cc.has("no-cache") {
+		return 0, false
+	}
+
+	// the response to an authenticated request is only shared when it says so, RFC 9111 section 3.5
+	if len(req.Header.Values("Authorization")) > 0 && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return 0, false
+	}
+
+	// s-maxage overrides max-age for shared caches
+	ttl, ok := cc.seconds("s-maxage")
+	if !ok {
+		ttl, ok = cc.seconds("max-age")
+	}
+	if ok {
+		return ttl, ttl > 0
+	}
+
+	// HTTP/1.0 fallbacks, Expires is only overridden by max-age and s-maxage
+	if len(cc) == 0 && pragmaNoCache(header) {
+		return 0, false
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			// an invalid date means already expired
+			return 0, false
+		}
+		date := now
+		if d, err := http.ParseTime(header.Get("Date")); err == nil {
+			date = d
+		}
+		ttl = expiresAt.Sub(date)
+		return ttl, ttl > 0
+	}
+	return defaultTTL, true
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCacheTTL(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	defaultTTL := time.Minute
+
+	cases := []struct {
+		header    http.Header
+		ttl       time.Duration
+		storeable bool
+	}{
+		{http.Header{}, defaultTTL, true},
+		{http.Header{"Cache-Control": {"no-store"}}, 0, false},
+		{http.Header{"Cache-Control": {"private, max-age=60"}}, 0, false},
+		{http.Header{"Cache-Control": {"no-cache"}}, 0, false},
+		{http.Header{"Cache-Control": {"public, max-age=30"}}, 30 * time.Second, true},
+		{http.Header{"Cache-Control": {"max-age=30, s-maxage=300"}}, 300 * time.Second, true},
+		{http.Header{"Cache-Control": {"max-age=0"}}, 0, false},
+		{http.Header{"Cache-Control": {"max-age=abc"}}, 0, false},
+		{http.Header{"Cache-Control": {"public"}, "Pragma": {"no-cache"}}, defaultTTL, true},
+		{http.Header{"Pragma": {"no-cache"}}, 0, false},
+		{http.Header{"Expires": {"Tue, 01 Jun 2021 00:10:00 GMT"}}, 10 * time.Minute, true},
+		{http.Header{"Expires": {"Tue, 01 Jun 2021 00:10:00 GMT"}, "Date": {"Tue, 01 Jun 2021 00:05:00 GMT"}}, 5 * time.Minute, true},
+		{http.Header{"Expires": {"0"}}, 0, false},
+		{http.Header{"Expires": {"Tue, 01 Jun 2021 00:10:00 GMT"}, "Cache-Control": {"max-age=5"}}, 5 * time.Second, true},
+		{http.Header{"Expires": {"Tue, 01 Jun 2021 00:00:05 GMT"}, "Cache-Control": {"public"}}, 5 * time.Second, true},
+		{http.Header{"Expires": {"0"}, "Cache-Control": {"public"}}, 0, false},
+	}
+	for _, tc := range cases {
+		ttl, storeable := responseCacheTTL(httptest.NewRequest(http.MethodGet, "/", nil), tc.header, defaultTTL, now)
+		assert.Equal(t, tc.storeable, storeable, tc.header)
+		if tc.storeable {
+			assert.Equal(t, tc.ttl, ttl, tc.header)
+		}
+	}
+}
+
+func TestResponseCacheTTLAuthorization(t *testing.T) {
+	now := time.Now()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	cases := []struct {
+		header    http.Header
+		storeable bool
+	}{
+		{http.Header{}, false},
+		{http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{http.Header{"Cache-Control": {"public, max-age=60"}}, true},
+		{http.Header{"Cache-Control": {"s-maxage=60"}}, true},
+		{http.Header{"Cache-Control": {"must-revalidate, max-age=60"}}, true},
+		{http.Header{"Cache-Control": {"private, must-revalidate"}}, false},
+	}
+	for _, tc := range cases {
+		_, storeable := responseCacheTTL(req, tc.header, time.Minute, now)
+		assert.Equal(t, tc.storeable, storeable, tc.header)
+	}
+}
+
+func TestParseRequestCacheControl(t *testing.T) {
+	assert.Equal(t, requestCacheControl{}, parseRequestCacheControl(http.Header{}))
+	assert.Equal(t, requestCacheControl{noCache: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"no-cache"}}))
+	assert.Equal(t, requestCacheControl{noCache: true, hasMaxAge: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"max-age=0"}}))
+	assert.Equal(t, requestCacheControl{maxAge: 10 * time.Second, hasMaxAge: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"max-age=10"}}))
+	assert.Equal(t, requestCacheControl{noCache: true}, parseRequestCacheControl(http.Header{"Pragma": {"no-cache"}}))
+	assert.Equal(t, requestCacheControl{maxAge: 10 * time.Second, hasMaxAge: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"max-age=10"}, "Pragma": {"no-cache"}}))
+	assert.Equal(t, requestCacheControl{minFresh: 5 * time.Second}, parseRequestCacheControl(http.Header{"Cache-Control": {"min-fresh=5"}}))
+	assert.Equal(t, requestCacheControl{maxStale: 5 * time.Second, hasMaxStale: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"max-stale=5"}}))
+	assert.Equal(t, requestCacheControl{maxStale: time.Duration(math.MaxInt64), hasMaxStale: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"max-stale"}}))
+	assert.Equal(t, requestCacheControl{noStore: true, onlyIfCached: true}, parseRequestCacheControl(http.Header{"Cache-Control": {"No-Store", "only-if-cached"}}))
+}
+
+func TestCacheControl(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("cc:%d:", time.Now().UnixNano())),
+		WithCacheControl(),
+	))
+	engine.GET("/cc", func(c *gin.Context) {
+		c.Header("Cache-Control", c.Query("cc"))
+		c.String(http.StatusOK, "%d", time.Now().UnixNano())
+	})
+
+	request := func(url string, cacheControl string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if cacheControl != "" {
+			req.Header.Set("Cache-Control", cacheControl)
+		}
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// only-if-cached never calls the backend
+	assert.Equal(t, http.StatusGatewayTimeout, request("/cc?cc=public", "only-if-cached").Code)
+
+	w1 := request("/cc?cc=public", "")
+	assert.Equal(t, w1.Body, request("/cc?cc=public", "").Body)
+	assert.Equal(t, w1.Body, request("/cc?cc=public", "only-if-cached").Body)
+
+	// no-cache goes to the backend and refreshes the entry
+	w2 := request("/cc?cc=public", "no-cache")
+	assert.NotEqual(t, w1.Body, w2.Body)
+	assert.Equal(t, w2.Body, request("/cc?cc=public", "").Body)
+
+	// no-store responses are never stored
+	w3 := request("/cc?cc=no-store", "")
+	assert.NotEqual(t, w3.Body, request("/cc?cc=no-store", "").Body)
+
+	// max-age sets the expiration
+	w4 := request("/cc?cc=max-age%3D1", "")
+	assert.Equal(t, w4.Body, request("/cc?cc=max-age%3D1", "").Body)
+	time.Sleep(1200 * time.Millisecond)
+	assert.NotEqual(t, w4.Body, request("/cc?cc=max-age%3D1", "").Body)
+}
+
+func TestCacheControlRequestFreshness(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("ccf:%d:", time.Now().UnixNano())),
+		WithCacheControl(),
+		WithStaleIfError(time.Minute),
+	))
+	engine.GET("/ccf", func(c *gin.Context) {
+		c.Header("Cache-Control", "max-age=1")
+		c.String(http.StatusOK, "%d", time.Now().UnixNano())
+	})
+
+	request := func(cacheControl string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ccf", nil)
+		if cacheControl != "" {
+			req.Header.Set("Cache-Control", cacheControl)
+		}
+		engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	body := request("")
+	assert.Equal(t, body, request("max-age=10"))
+
+	// min-fresh refuses the entry expiring within a second
+	body2 := request("min-fresh=10")
+	assert.NotEqual(t, body, body2)
+
+	time.Sleep(1200 * time.Millisecond)
+
+	// max-stale accepts the expired entry kept by the store
+	assert.Equal(t, body2, request("max-stale"))
+	assert.Equal(t, body2, request("max-stale=10"))
+	assert.NotEqual(t, body2, request("max-stale=0"))
+}
+
+func TestCacheControlAuthorization(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("cca:%d:", time.Now().UnixNano())),
+		WithCacheControl(),
+	))
+	engine.GET("/cca", func(c *gin.Context) {
+		c.Header("Cache-Control", c.Query("cc"))
+		c.String(http.StatusOK, "%s:%d", c.GetHeader("Authorization"), time.Now().UnixNano())
+	})
+
+	request := func(url string, authorization string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", authorization)
+		engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// the response of alice must not be served to bob
+	alice := request("/cca?cc=max-age%3D60", "alice")
+	assert.NotEqual(t, alice, request("/cca?cc=max-age%3D60", "bob"))
+
+	// unless it is explicitly public
+	alice = request("/cca?cc=public", "alice")
+	assert.Equal(t, alice, request("/cca?cc=public", "bob"))
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -184,6 +185,51 @@ func TestCacheByRequestURIIgnoreOrder(t *testing.T) {
 	assert.NotEqual(t, w3.Body, w1.Body)
 }
 
+func TestCacheNamespace(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+	cacheMiddleware := Cache(memoryStore, 10*time.Second, WithCacheStrategyByRequest(func(c *gin.Context) (bool, Strategy) {
+		return true, Strategy{CacheKey: c.Request.RequestURI, Namespace: "/cache"}
+	}))
+
+	w1 := mockHttpRequest(cacheMiddleware, "/cache?uid=u1", true)
+	w2 := mockHttpRequest(cacheMiddleware, "/cache?uid=u1", true)
+	assert.Equal(t, w1.Body, w2.Body)
+
+	require.NoError(t, persistence.NewNamespace(memoryStore, "/cache").Invalidate(context.TODO()))
+
+	w3 := mockHttpRequest(cacheMiddleware, "/cache?uid=u1", true)
+	assert.NotEqual(t, w1.Body, w3.Body)
+}
+
+func TestShouldRefreshEarly(t *testing.T) {
+	now := time.Now()
+	respCache := &ResponseCache{FreshUntil: now.Add(time.Hour), ComputeDuration: time.Millisecond}
+	assert.False(t, respCache.shouldRefreshEarly(now, 0))
+	assert.False(t, respCache.shouldRefreshEarly(now, 1))
+
+	respCache.FreshUntil = now.Add(time.Nanosecond)
+	respCache.ComputeDuration = time.Second
+	assert.True(t, respCache.shouldRefreshEarly(now, 1e9))
+
+	// gaps past the range of a Duration don't overflow
+	respCache.FreshUntil = now.Add(time.Hour)
+	assert.True(t, respCache.shouldRefreshEarly(now, 1e300))
+}
+
+func TestCacheEarlyExpiration(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+	cacheURIMiddleware := CacheByRequestURI(memoryStore, 10*time.Second,
+		WithPrefixKey(fmt.Sprintf("xfetch:%d:", time.Now().UnixNano())),
+		WithEarlyExpiration(1e9),
+		WithExpireJitter(time.Second),
+	)
+
+	// the computation is so long compared to the expiry that every request refreshes it
+	w1 := mockHttpRequest(cacheURIMiddleware, "/cache?uid=u1", true)
+	w2 := mockHttpRequest(cacheURIMiddleware, "/cache?uid=u1", true)
+	assert.NotEqual(t, w1.Body, w2.Body)
+}
+
 var newRedisStore = func(defaultExpiration time.Duration) persistence.CacheStore {
 	var redisTestServer = "localhost:6379"
 	c, err := net.Dial("tcp", redisTestServer)
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// URLCanonicalizer rewrites request URIs so that equivalent URIs share one cache key.
+// Percent-encoding is always normalized and query parameters are sorted, the other rules are opt-in.
+// Parameter names of AllowQuery, DenyQuery and FoldCase may end with * to match a prefix, like utm_*.
+type URLCanonicalizer struct {
+	// AllowQuery only keeps these query parameters when not empty
+	AllowQuery []string
+	// DenyQuery drops these query parameters, like tracking ones
+	DenyQuery []string
+	// FoldCase lower cases the values of these query parameters
+	FoldCase []string
+	// CleanPath removes dot segments, duplicate and trailing slashes from the path
+	CleanPath bool
+	// DropEmpty drops query parameters without value
+	DropEmpty bool
+	// Dedupe keeps a single copy of repeated parameter values, a=1&a=1 becomes a=1
+	Dedupe bool
+}
+
+// errNoPath means the request URI is not a path, like * or an opaque URI
+var errNoPath = errors.New("cache: request uri without path")
+
+// Canonicalize returns the canonical path and query of the request URI
+func (u *URLCanonicalizer) Canonicalize(requestURI string) (string, error) {
+	parsedUrl, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return "", err
+	}
+	escapedPath := parsedUrl.EscapedPath()
+	if parsedUrl.Opaque != "" || (escapedPath != "" && escapedPath[0] != '/') {
+		return "", errNoPath
+	}
+
+	p, err := u.canonicalPath(escapedPath)
+	if err != nil {
+		return "", err
+	}
+
+	query, err := u.canonicalQuery(parsedUrl.RawQuery)
+	if err != nil {
+		return "", err
+	}
+	if query == "" {
+		return p, nil
+	}
+	return p + "?" + query, nil
+}
+
+// canonicalPath decodes and re-encodes every segment, so %41 and A are the same
+// while an encoded slash stays distinct from a separator
+func (u *URLCanonicalizer) canonicalPath(escapedPath string) (string, error) {
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", err
+		}
+		segments[i] = url.PathEscape(decoded)
+	}
+	p := strings.Join(segments, "/")
+
+	if u.CleanPath {
+		p = path.Clean("/" + p)
+	}
+	if p == "" {
+		p = "/"
+	}
+	return p, nil
+}
+
+func (u *URLCanonicalizer) canonicalQuery(rawQuery string) (string, error) {
+	if rawQuery == "" {
+		return "", nil
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if len(u.AllowQuery) > 0 && !matchParam(u.AllowQuery, key) {
+			continue
+		}
+		if matchParam(u.DenyQuery, key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		vals := values[key]
+		if matchParam(u.FoldCase, key) {
+			for i, val := range vals {
+				vals[i] = strings.ToLower(val)
+			}
+		}
+		sort.Strings(vals)
+
+		for i, val := range vals {
+			if u.DropEmpty && val == "" {
+				continue
+			}
+			if u.Dedupe && i > 0 && vals[i-1] == val {
+				continue
+			}
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(key))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(val))
+		}
+	}
+	return b.String(), nil
+}
+
+// matchParam reports whether name is in names, entries ending with * match a prefix
+func matchParam(names []string, name string) bool {
+	for _, pattern := range names {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLCanonicalizer(t *testing.T) {
+	canonicalizer := &URLCanonicalizer{
+		DenyQuery: []string{"utm_*", "fbclid"},
+		FoldCase:  []string{"lang"},
+		CleanPath: true,
+		DropEmpty: true,
+		Dedupe:    true,
+	}
+
+	cases := map[string]string{
+		"/a?x=%41":                        "/a?x=A",
+		"/%61/b/":                         "/a/b",
+		"//a/./c/../b":                    "/a/b",
+		"/a%2Fb":                          "/a%2Fb",
+		"/a?b=2&a=1":                      "/a?a=1&b=2",
+		"/a?x=1&utm_source=mail&fbclid=z": "/a?x=1",
+		"/a?lang=EN":                      "/a?lang=en",
+		"/a?x=&y":                         "/a",
+		"/a?x=1&x=1&x=2":                  "/a?x=1&x=2",
+		"/a?q=a+b":                        "/a?q=a+b",
+		"/a?q=a%20b":                      "/a?q=a+b",
+		"/a?q=a%26b":                      "/a?q=a%26b",
+		"/":                               "/",
+	}
+	for uri, expected := range cases {
+		canonical, err := canonicalizer.Canonicalize(uri)
+		require.NoError(t, err, uri)
+		assert.Equal(t, expected, canonical, uri)
+	}
+
+	_, err := canonicalizer.Canonicalize("/a?x=%zz")
+	assert.Error(t, err)
+	_, err = canonicalizer.Canonicalize("*")
+	assert.Error(t, err)
+}
+
+func TestURLCanonicalizerAllowQuery(t *testing.T) {
+	canonicalizer := &URLCanonicalizer{AllowQuery: []string{"page", "filter_*"}}
+
+	canonical, err := canonicalizer.Canonicalize("/list?session=1&page=2&filter_name=a&sort=b")
+	require.NoError(t, err)
+	assert.Equal(t, "/list?filter_name=a&page=2", canonical)
+
+	// without CleanPath the trailing slash is kept
+	canonical, err = canonicalizer.Canonicalize("/list/?x=1")
+	require.NoError(t, err)
+	assert.Equal(t, "/list/", canonical)
+}
+
+func TestCacheByRequestURIWithCanonicalizer(t *testing.T) {
+	cacheURIMiddleware := CacheByRequestURI(newRedisStore(1*time.Minute), 3*time.Second, WithURLCanonicalizer(&URLCanonicalizer{
+		DenyQuery: []string{"utm_*"},
+		CleanPath: true,
+	}), WithPrefixKey("canonical:"))
+
+	w1 := mockHttpRequest(cacheURIMiddleware, "/cache?uid=%75%31", true)
+	w2 := mockHttpRequest(cacheURIMiddleware, "/%63ache?utm_source=mail&uid=u1", true)
+	w3 := mockHttpRequest(cacheURIMiddleware, "/cache?uid=u2", true)
+
+	assert.Equal(t, w1.Body, w2.Body)
+	assert.NotEqual(t, w1.Body, w3.Body)
+}
+
+func FuzzURLCanonicalizer(f *testing.F) {
+	for _, seed := range []string{
+		"/a?x=%41", "/a/../b/?utm_source=x&b=2&a=1", "/a%2Fb?q=a+b", "/?x=&x=&y", "//a//b/./c", "/a?lang=%C3%89",
+	} {
+		f.Add(seed)
+	}
+
+	canonicalizers := []*URLCanonicalizer{
+		{},
+		{DenyQuery: []string{"utm_*"}, FoldCase: []string{"lang"}, CleanPath: true, DropEmpty: true, Dedupe: true},
+		{AllowQuery: []string{"a", "b*"}, FoldCase: []string{"*"}},
+	}
+
+	f.Fuzz(func(t *testing.T, uri string) {
+		for _, canonicalizer := range canonicalizers {
+			canonical, err := canonicalizer.Canonicalize(uri)
+			if err != nil {
+				continue
+			}
+			again, err := canonicalizer.Canonicalize(canonical)
+			if err != nil {
+				t.Fatalf("canonical form %q of %q is invalid: %s", canonical, uri, err)
+			}
+			if again != canonical {
+				t.Fatalf("canonical form of %q is not stable: %q then %q", uri, canonical, again)
+			}
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the values of the X-Cache header
+const (
+	CacheStatusHit    = "HIT"
+	CacheStatusMiss   = "MISS"
+	CacheStatusShared = "SHARED"
+	CacheStatusStale  = "STALE"
+)
+
+// diagnosticHeaders are set by the middleware itself and never stored
+var diagnosticHeaders = []string{"Age", "X-Cache", "X-Cache-Key"}
+
+func stripDiagnosticHeaders(header http.Header) {
+	for _, key := range diagnosticHeaders {
+		header.Del(key)
+	}
+}
+
+// writeCacheStatus sets the diagnostic headers of the response, respCache is nil on a cache miss
+func writeCacheStatus(c *gin.Context, cfg *Config, status string, respCache *ResponseCache, cacheKey string) {
+	header := c.Writer.Header()
+	if cfg.cacheHeaders {
+		header.Set("X-Cache", status)
+		if respCache != nil && !respCache.StoredAt.IsZero() {
+			age := time.Since(respCache.StoredAt) / time.Second
+			if age < 0 {
+				age = 0
+			}
+			header.Set("Age", strconv.FormatInt(int64(age), 10))
+		}
+	}
+
+	if cfg.debugKeyHeader != "" {
+		secret := c.Request.Header.Get(cfg.debugKeyHeader)
+		if secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(cfg.debugKeySecret)) == 1 {
+			header.Set("X-Cache-Key", cacheKey)
+		}
+	}
+}
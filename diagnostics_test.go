@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+	prefix := fmt.Sprintf("diagnostics:%d:", time.Now().UnixNano())
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(prefix),
+		WithCacheHeaders(),
+		WithDebugCacheKey("X-Cache-Debug", "secret"),
+	))
+	engine.GET("/diagnostics", func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "value")
+	})
+
+	request := func(header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/diagnostics", nil)
+		req.Header = header
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	var statuses []string
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := request(http.Header{})
+			mu.Lock()
+			statuses = append(statuses, w.Header().Get("X-Cache"))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Strings(statuses)
+	assert.Equal(t, []string{CacheStatusMiss, CacheStatusShared}, statuses)
+
+	time.Sleep(1100 * time.Millisecond)
+	w := request(http.Header{})
+	assert.Equal(t, CacheStatusHit, w.Header().Get("X-Cache"))
+	assert.Equal(t, "1", w.Header().Get("Age"))
+	assert.Empty(t, w.Header().Get("X-Cache-Key"))
+
+	w = request(http.Header{"X-Cache-Debug": {"secret"}})
+	assert.Equal(t, prefix+"/diagnostics", w.Header().Get("X-Cache-Key"))
+
+	w = request(http.Header{"X-Cache-Debug": {"guess"}})
+	assert.Empty(t, w.Header().Get("X-Cache-Key"))
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	// EncodingGzip stores responses compressed with gzip
+	EncodingGzip = "gzip"
+	// EncodingBrotli stores responses compressed with brotli
+	EncodingBrotli = "br"
+
+	encodingIdentity = "identity"
+)
+
+// contentEncoder compresses and decompresses a stored body
+type contentEncoder struct {
+	encode func(w io.Writer) io.WriteCloser
+	decode func(r io.Reader) (io.Reader, error)
+}
+
+var contentEncoders = map[string]contentEncoder{
+	EncodingGzip: {
+		encode: func(w io.Writer) io.WriteCloser {
+			return gzip.NewWriter(w)
+		},
+		decode: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	EncodingBrotli: {
+		encode: func(w io.Writer) io.WriteCloser {
+			return brotli.NewWriter(w)
+		},
+		decode: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+	},
+}
+
+// encodeBody compresses the body of the response with encoding before it is stored,
+// responses already encoded by the handler are kept as is
+func (c *ResponseCache) encodeBody(encoding string) error {
+	if c.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	encoder, ok := contentEncoders[encoding]
+	if !ok {
+		return nil
+	}
+
+	var b bytes.Buffer
+	w := encoder.encode(&b)
+	if _, err := w.Write(c.Data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	c.Data = b.Bytes()
+	c.Header.Set("Content-Encoding", encoding)
+	c.Header.Del("Content-Length")
+	if etag := c.Header.Get("ETag"); etag != "" {
+		c.Header.Set("ETag", encodedETag(etag, encoding))
+	}
+	addVary(c.Header, "Accept-Encoding")
+	return nil
+}
+
+// negotiateEncoding returns the response to send to a client with the given Accept-Encoding,
+// the body is decompressed if the client doesn't accept the stored encoding
+func (c *ResponseCache) negotiateEncoding(acceptEncoding []string) (*ResponseCache, error) {
+	encoding := c.Header.Get("Content-Encoding")
+	encoder, ok := contentEncoders[encoding]
+	if !ok || acceptsEncoding(acceptEncoding, encoding) {
+		return c, nil
+	}
+
+	r, err := encoder.decode(bytes.NewReader(c.Data))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := *c
+	decoded.Header = c.Header.Clone()
+	decoded.Header.Del("Content-Encoding")
+	// the length set by a handler encoding the body itself is the one of the encoded body
+	decoded.Header.Del("Content-Length")
+	if etag := decoded.Header.Get("ETag"); etag != "" {
+		decoded.Header.Set("ETag", decodedETag(etag, encoding))
+	}
+	decoded.Data = data
+	return &decoded, nil
+}
+
+// encodedETag returns the ETag of the body encoded with encoding. A strong ETag identifies the bytes
+// sent, so each encoding gets its own and ranges of different encodings are never mixed.
+func encodedETag(etag string, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// decodedETag returns the ETag of the decoded body, the ETag set by the handler on a body it encoded
+// itself only becomes weak, as the decoded bytes differ
+func decodedETag(etag string, encoding string) string {
+	suffix := "-" + encoding + `"`
+	if strings.HasSuffix(etag, suffix) {
+		return etag[:len(etag)-len(suffix)] + `"`
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return "W/" + etag
+}
+
+// acceptsEncoding reports whether the Accept-Encoding values allow encoding, see RFC 9110 section 12.5.3.
+// Without Accept-Encoding, only identity is considered acceptable.
+func acceptsEncoding(acceptEncoding []string, encoding string) bool {
+	wildcard := -1.0
+	for _, value := range acceptEncoding {
+		for _, element := range strings.Split(value, ",") {
+			coding, q := parseQualityValue(element)
+			switch {
+			case strings.EqualFold(coding, encoding):
+				return q > 0
+			case coding == "*":
+				wildcard = q
+			}
+		}
+	}
+	if wildcard >= 0 {
+		return wildcard > 0
+	}
+	return encoding == encodingIdentity
+}
+
+// parseQualityValue splits an element like "gzip;q=0.5" into its value and weight
+func parseQualityValue(element string) (string, float64) {
+	parts := strings.Split(element, ";")
+	value, q := strings.TrimSpace(parts[0]), 1.0
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+			if weight, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = weight
+			}
+		}
+	}
+	return value, q
+}
+
+// addVary adds name to the Vary header unless it is already listed
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, listed := range strings.Split(value, ",") {
+			listed = strings.TrimSpace(listed)
+			if listed == "*" || strings.EqualFold(listed, name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	assert.True(t, acceptsEncoding([]string{"gzip, deflate, br"}, "br"))
+	assert.True(t, acceptsEncoding([]string{"deflate", "GZIP;q=0.5"}, "gzip"))
+	assert.False(t, acceptsEncoding([]string{"gzip;q=0"}, "gzip"))
+	assert.True(t, acceptsEncoding([]string{"*"}, "br"))
+	assert.False(t, acceptsEncoding([]string{"br, *;q=0"}, "gzip"))
+	assert.False(t, acceptsEncoding(nil, "gzip"))
+	assert.True(t, acceptsEncoding(nil, encodingIdentity))
+}
+
+func TestCacheContentEncoding(t *testing.T) {
+	decoders := map[string]func(r io.Reader) (io.Reader, error){
+		EncodingGzip: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		EncodingBrotli: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+	}
+
+	for encoding, decode := range decoders {
+		memoryStore := newRedisStore(1 * time.Minute)
+
+		engine := gin.New()
+		engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+			WithPrefixKey(fmt.Sprintf("encoding:%d:", time.Now().UnixNano())),
+			WithContentEncoding(encoding),
+			WithVary(),
+			WithETag(),
+		))
+		engine.GET("/encoding", func(c *gin.Context) {
+			c.Header("Vary", "Accept-Encoding")
+			c.String(http.StatusOK, "%d", rand.Int())
+		})
+
+		request := func(acceptEncoding string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/encoding", nil)
+			if acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+			engine.ServeHTTP(w, req)
+			return w
+		}
+
+		w := request("")
+		body := w.Body.String()
+		etag := computeETag([]byte(body))
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+
+		// one stored entry serves every client, each encoding has its own ETag
+		w = request("gzip, br")
+		assert.Equal(t, encoding, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, encodedETag(etag, encoding), w.Header().Get("ETag"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+		r, err := decode(bytes.NewReader(w.Body.Bytes()))
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(decoded))
+
+		w = request("deflate")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, body, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+
+		// the ETag of the decoded body doesn't validate the encoded one
+		req := httptest.NewRequest(http.MethodGet, "/encoding", nil)
+		req.Header.Set("Accept-Encoding", encoding)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		req.Header.Set("Accept-Encoding", "deflate")
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	}
+}
+
+func TestCacheContentEncodingByHandler(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	body := strings.Repeat("encoded by the handler ", 30)
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	_, err := gz.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	compressed := b.Bytes()
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("encoding_handler:%d:", time.Now().UnixNano())),
+		WithContentEncoding(EncodingGzip),
+	))
+	engine.GET("/encoded", func(c *gin.Context) {
+		c.DataFromReader(http.StatusOK, int64(len(compressed)), "text/plain", bytes.NewReader(compressed),
+			map[string]string{"Content-Encoding": EncodingGzip})
+	})
+
+	request := func(acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/encoded", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	request(EncodingGzip)
+	w := request(EncodingGzip)
+	assert.Equal(t, compressed, w.Body.Bytes())
+	assert.Equal(t, strconv.Itoa(len(compressed)), w.Header().Get("Content-Length"))
+
+	// the decoded body doesn't keep the length of the encoded one
+	w = request("identity")
+	assert.Equal(t, body, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Header().Get("Content-Length"))
+}
+
+func TestEncodingETag(t *testing.T) {
+	assert.Equal(t, `"abc-gzip"`, encodedETag(`"abc"`, EncodingGzip))
+	assert.Equal(t, `W/"abc-br"`, encodedETag(`W/"abc"`, EncodingBrotli))
+	assert.Equal(t, `"abc"`, decodedETag(`"abc-gzip"`, EncodingGzip))
+	// the handler's own ETag of an encoded body
+	assert.Equal(t, `W/"abc"`, decodedETag(`"abc"`, EncodingGzip))
+}
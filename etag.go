@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notModifiedHeaders the headers sent with a 304 response, see RFC 9110 section 15.4.5
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Vary"}
+
+// computeETag returns a strong ETag built from the SHA-256 of data
+func computeETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// fillETag sets the ETag of the response, unless the handler provided its own validator
+func (c *ResponseCache) fillETag() {
+	if c.Header.Get("ETag") != "" || c.Header.Get("Last-Modified") != "" {
+		return
+	}
+	if c.Header == nil {
+		c.Header = http.Header{}
+	}
+	c.Header.Set("ETag", computeETag(c.Data))
+}
+
+// isNotModified evaluates the If-None-Match and If-Modified-Since preconditions of the request
+// against the cached response, see RFC 9110 section 13.2.2
+func isNotModified(req *http.Request, respCache *ResponseCache) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if respCache.Status != http.StatusOK {
+		return false
+	}
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		etag := respCache.Header.Get("ETag")
+		return etag != "" && etagMatch(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(respCache.Header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
+
+// etagMatch reports whether etag is in the If-None-Match list, using the weak comparison
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func replyNotModified(c *gin.Context, respCache *ResponseCache) {
+	for _, key := range notModifiedHeaders {
+		if values := respCache.Header.Values(key); len(values) > 0 {
+			c.Writer.Header()[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	c.Writer.WriteHeader(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+}
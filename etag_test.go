@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNotModified(t *testing.T) {
+	respCache := &ResponseCache{
+		Status: http.StatusOK,
+		Header: http.Header{"Etag": {`"abc"`}, "Last-Modified": {"Tue, 01 Jun 2021 00:00:00 GMT"}},
+	}
+
+	cases := []struct {
+		header      http.Header
+		notModified bool
+	}{
+		{http.Header{}, false},
+		{http.Header{"If-None-Match": {`"abc"`}}, true},
+		{http.Header{"If-None-Match": {`"xyz", W/"abc"`}}, true},
+		{http.Header{"If-None-Match": {"*"}}, true},
+		{http.Header{"If-None-Match": {`"xyz"`}}, false},
+		// If-Modified-Since is ignored when If-None-Match is present
+		{http.Header{"If-None-Match": {`"xyz"`}, "If-Modified-Since": {"Tue, 01 Jun 2021 00:00:00 GMT"}}, false},
+		{http.Header{"If-Modified-Since": {"Tue, 01 Jun 2021 00:00:00 GMT"}}, true},
+		{http.Header{"If-Modified-Since": {"Mon, 31 May 2021 00:00:00 GMT"}}, false},
+		{http.Header{"If-Modified-Since": {"invalid"}}, false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header = tc.header
+		assert.Equal(t, tc.notModified, isNotModified(req, respCache), tc.header)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+	assert.False(t, isNotModified(req, respCache))
+}
+
+func TestCacheETag(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("etag:%d:", time.Now().UnixNano())),
+		WithETag(),
+	))
+	engine.GET("/etag", func(c *gin.Context) {
+		c.String(http.StatusOK, "value")
+	})
+	engine.GET("/last_modified", func(c *gin.Context) {
+		c.Header("Last-Modified", "Tue, 01 Jun 2021 00:00:00 GMT")
+		c.String(http.StatusOK, "value")
+	})
+
+	request := func(url string, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header = header
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// the first response, sent by the backend, has the ETag of the stored one
+	w := request("/etag", http.Header{})
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, computeETag([]byte("value")), etag)
+	assert.Equal(t, "value", w.Body.String())
+
+	w = request("/etag", http.Header{})
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = request("/etag", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = request("/etag", http.Header{"If-None-Match": {`"other"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "value", w.Body.String())
+
+	// the validator of the handler is kept
+	w = request("/last_modified", http.Header{})
+	assert.Empty(t, w.Header().Get("ETag"))
+	w = request("/last_modified", http.Header{"If-Modified-Since": {"Wed, 02 Jun 2021 00:00:00 GMT"}})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
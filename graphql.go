@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// errGraphQLNotQuery means the selected operation is a mutation or a subscription
+	errGraphQLNotQuery = errors.New("cache: graphql operation is not a query")
+
+	// errGraphQLOperation means the operation to execute can't be told from the document
+	errGraphQLOperation = errors.New("cache: graphql operation not found")
+
+	// errGraphQLSyntax means the document can't be tokenized
+	errGraphQLSyntax = errors.New("cache: graphql syntax error")
+
+	// errGraphQLPersistedQuery means the request only carries a persisted query hash that can't be resolved
+	errGraphQLPersistedQuery = errors.New("cache: graphql persisted query not found")
+)
+
+// GraphQL builds cache keys of GraphQL requests, sent as a JSON POST body or as GET query parameters.
+// The key is made of the normalized query document, the operation name and the canonical variables,
+// so formatting and variable order don't matter. Mutations and subscriptions are never cached.
+type GraphQL struct {
+	// PersistedQuery returns the document registered under the sha256Hash of the persistedQuery extension.
+	// Requests only carrying a hash are not cached without it, as their operation type can't be checked.
+	PersistedQuery func(hash string) (string, bool)
+
+	// MaxBodySize the largest POST body read, the default is 1MB
+	MaxBodySize int64
+}
+
+// graphQLRequest the GraphQL over HTTP request
+type graphQLRequest struct {
+	Query         string          `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+	Extensions    struct {
+		PersistedQuery struct {
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// Strategy returns a GetCacheStrategyByRequest caching GraphQL queries,
+// other requests and the ones that can't be parsed are not cached
+func (g *GraphQL) Strategy() GetCacheStrategyByRequest {
+	return func(c *gin.Context) (bool, Strategy) {
+		key, err := g.Key(c)
+		if err != nil {
+			return false, Strategy{}
+		}
+		return true, Strategy{CacheKey: key}
+	}
+}
+
+// Key returns the cache key of the GraphQL request, or an error if it must not be cached
+func (g *GraphQL) Key(c *gin.Context) (string, error) {
+	req, err := g.parseRequest(c.Request)
+	if err != nil {
+		return "", err
+	}
+
+	query := req.Query
+	if query == "" {
+		hash := req.Extensions.PersistedQuery.Sha256Hash
+		if hash == "" || g.PersistedQuery == nil {
+			return "", errGraphQLPersistedQuery
+		}
+		var ok bool
+		if query, ok = g.PersistedQuery(hash); !ok {
+			return "", errGraphQLPersistedQuery
+		}
+	}
+
+	tokens, err := tokenizeGraphQL(query)
+	if err != nil {
+		return "", err
+	}
+	operationType, err := graphQLOperationType(tokens, req.OperationName)
+	if err != nil {
+		return "", err
+	}
+	if operationType != "query" {
+		return "", errGraphQLNotQuery
+	}
+
+	variables := []byte("{}")
+	if len(req.Variables) > 0 && string(req.Variables) != "null" {
+		if variables, err = canonicalizeJSON(req.Variables); err != nil {
+			return "", err
+		}
+	}
+
+	w := &keyWriter{}
+	w.field("query", normalizeGraphQL(tokens))
+	w.field("operation", req.OperationName)
+	w.field("variables", string(variables))
+	sum := sha256.Sum256([]byte(w.String()))
+	return "graphql:" + c.Request.URL.Path + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func (g *GraphQL) parseRequest(r *http.Request) (*graphQLRequest, error) {
+	req := &graphQLRequest{}
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			req.Variables = json.RawMessage(variables)
+		}
+		if extensions := query.Get("extensions"); extensions != "" {
+			if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+				return nil, err
+			}
+		}
+	case http.MethodPost:
+		maxSize := g.MaxBodySize
+		if maxSize <= 0 {
+			maxSize = defaultMaxBodySize
+		}
+		body, err := readRequestBody(r, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		// batched requests are arrays and fail here
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errGraphQLOperation
+	}
+	return req, nil
+}
+
+// graphQLToken a lexical token of a GraphQL document, see the Lexical Tokens section of the spec
+type graphQLToken struct {
+	value string
+	// word names and numbers, which need a space between them
+	word bool
+}
+
+// tokenizeGraphQL splits the document into its tokens, dropping whitespace, commas and comments
+func tokenizeGraphQL(doc string) ([]graphQLToken, error) {
+	var tokens []graphQLToken
+	for i := 0; i < len(doc); {
+		ch := doc[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case strings.HasPrefix(doc[i:], "\ufeff"):
+			i += len("\ufeff")
+		case ch == '#':
+			for i < len(doc) && doc[i] != '\n' && doc[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(doc[i:], "..."):
+			tokens = append(tokens, graphQLToken{value: "..."})
+			i += 3
+		case strings.IndexByte("!$&()[]{}:=@|", ch) >= 0:
+			tokens = append(tokens, graphQLToken{value: doc[i : i+1]})
+			i++
+		case strings.HasPrefix(doc[i:], `"""`):
+			end := i + 3
+			for {
+				j := strings.Index(doc[end:], `"""`)
+				if j < 0 {
+					return nil, errGraphQLSyntax
+				}
+				end += j
+				if doc[end-1] != '\\' {
+					break
+				}
+				end += 3
+			}
+			tokens = append(tokens, graphQLToken{value: doc[i : end+3]})
+			i = end + 3
+		case ch == '"':
+			end := i + 1
+			for ; end < len(doc) && doc[end] != '"'; end++ {
+				if doc[end] == '\\' {
+					end++
+				} else if doc[end] == '\n' || doc[end] == '\r' {
+					return nil, errGraphQLSyntax
+				}
+			}
+			if end >= len(doc) {
+				return nil, errGraphQLSyntax
+			}
+			tokens = append(tokens, graphQLToken{value: doc[i : end+1]})
+			i = end + 1
+		case isGraphQLNameChar(ch) || ch == '-':
+			end := i + 1
+			for end < len(doc) && (isGraphQLNameChar(doc[end]) || doc[end] == '.' ||
+				((doc[end] == '-' || doc[end] == '+') && (doc[end-1] == 'e' || doc[end-1] == 'E'))) {
+				end++
+			}
+			tokens = append(tokens, graphQLToken{value: doc[i:end], word: true})
+			i = end
+		default:
+			return nil, errGraphQLSyntax
+		}
+	}
+	return tokens, nil
+}
+
+func isGraphQLNameChar(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// normalizeGraphQL joins the tokens with a single space where one is needed
+func normalizeGraphQL(tokens []graphQLToken) string {
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token.word && tokens[i-1].word {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token.value)
+	}
+	return b.String()
+}
+
+// graphQLOperationType returns the type of the operation executed by the request,
+// the named one or the only operation of the document
+func graphQLOperationType(tokens []graphQLToken, operationName string) (string, error) {
+	type operation struct {
+		typ, name string
+	}
+	var operations []operation
+
+	braces, parens := 0, 0
+	inDefinition := false
+	for i, token := range tokens {
+		switch token.value {
+		case "(":
+			parens++
+		case ")":
+			parens--
+		case "{":
+			if parens == 0 {
+				if braces == 0 {
+					if !inDefinition {
+						// shorthand query
+						operations = append(operations, operation{typ: "query"})
+					}
+					inDefinition = false
+				}
+				braces++
+			}
+		case "}":
+			if parens == 0 {
+				braces--
+			}
+		default:
+			if braces != 0 || parens != 0 || inDefinition || !token.word {
+				continue
+			}
+			switch token.value {
+			case "query", "mutation", "subscription":
+				op := operation{typ: token.value}
+				if i+1 < len(tokens) && tokens[i+1].word {
+					op.name = tokens[i+1].value
+				}
+				operations = append(operations, op)
+			case "fragment":
+			default:
+				return "", errGraphQLOperation
+			}
+			inDefinition = true
+		}
+	}
+
+	if operationName != "" {
+		for _, op := range operations {
+			if op.name == operationName {
+				return op.typ, nil
+			}
+		}
+		return "", errGraphQLOperation
+	}
+	if len(operations) != 1 {
+		return "", errGraphQLOperation
+	}
+	return operations[0].typ, nil
+}
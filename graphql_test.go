@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func graphQLKey(g *GraphQL, method string, body string, query url.Values) (string, error) {
+	target := "/graphql"
+	if query != nil {
+		target += "?" + query.Encode()
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	return g.Key(c)
+}
+
+func TestGraphQLKey(t *testing.T) {
+	g := &GraphQL{}
+
+	key, err := graphQLKey(g, http.MethodPost, `{"query":"query User($id: ID!) { user(id: $id) { id, name } }","operationName":"User","variables":{"id":"1","lang":"en"}}`, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "graphql:/graphql:"))
+
+	// formatting, comments and variable order don't matter
+	same, err := graphQLKey(g, http.MethodPost, `{"query":"# fetch a user\nquery User($id: ID!) {\n  user(id: $id) {\n    id\n    name\n  }\n}","operationName":"User","variables":{"lang":"en","id":"1"}}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	// GET requests share the key of POST ones
+	same, err = graphQLKey(g, http.MethodGet, "", url.Values{
+		"query":         {"query User($id: ID!) { user(id: $id) { id name } }"},
+		"operationName": {"User"},
+		"variables":     {`{"id":"1","lang":"en"}`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	for _, body := range []string{
+		`{"query":"query User($id: ID!) { user(id: $id) { id } }","operationName":"User","variables":{"id":"1","lang":"en"}}`,
+		`{"query":"query User($id: ID!) { user(id: $id) { id, name } }","operationName":"User","variables":{"id":"2","lang":"en"}}`,
+		`{"query":"query User($id: ID!) { user(id: $id) { id, name } }","operationName":"User","variables":{"id":1,"lang":"en"}}`,
+		`{"query":"query User($id: ID!) { user(id: $id) { id, name(format: \"a b\") } }","operationName":"User","variables":{"id":"1","lang":"en"}}`,
+	} {
+		other, err := graphQLKey(g, http.MethodPost, body, nil)
+		require.NoError(t, err, body)
+		assert.NotEqual(t, key, other, body)
+	}
+}
+
+func TestGraphQLOperations(t *testing.T) {
+	g := &GraphQL{}
+
+	cases := map[string]error{
+		`{"query":"{ users { id } }"}`:                                            nil,
+		`{"query":"query { users { id } }","variables":null}`:                     nil,
+		`{"query":"mutation { addUser(name: \"a\") { id } }"}`:                    errGraphQLNotQuery,
+		`{"query":"subscription OnUser { user { id } }"}`:                         errGraphQLNotQuery,
+		`{"query":"query A { a } mutation B { b }","operationName":"A"}`:          nil,
+		`{"query":"query A { a } mutation B { b }","operationName":"B"}`:          errGraphQLNotQuery,
+		`{"query":"query A { a } mutation B { b }"}`:                              errGraphQLOperation,
+		`{"query":"query A { a }","operationName":"C"}`:                           errGraphQLOperation,
+		`{"query":"query A($f: In = {query: 1}) { a(mutation: {b: 1}) }"}`:        nil,
+		`{"query":"query A { ...F } fragment F on Query { mutation }"}`:           nil,
+		`{"query":"query A { a(s: \"unterminated) }"}`:                            errGraphQLSyntax,
+		`{"query":"query A { a(s: \"\"\"block \\\"\"\" mutation\"\"\") { b } }"}`: nil,
+	}
+	for body, expected := range cases {
+		_, err := graphQLKey(g, http.MethodPost, body, nil)
+		assert.Equal(t, expected, err, body)
+	}
+
+	// batched requests are not cached
+	_, err := graphQLKey(g, http.MethodPost, `[{"query":"{ a }"}]`, nil)
+	assert.Error(t, err)
+}
+
+func TestGraphQLPersistedQuery(t *testing.T) {
+	body := `{"operationName":"Users","extensions":{"persistedQuery":{"version":1,"sha256Hash":"%s"}}}`
+
+	_, err := graphQLKey(&GraphQL{}, http.MethodPost, fmt.Sprintf(body, "abc"), nil)
+	assert.Equal(t, errGraphQLPersistedQuery, err)
+
+	g := &GraphQL{PersistedQuery: func(hash string) (string, bool) {
+		switch hash {
+		case "users":
+			return "query Users { users { id } }", true
+		case "add":
+			return "mutation Users { addUser { id } }", true
+		}
+		return "", false
+	}}
+
+	key, err := graphQLKey(g, http.MethodPost, fmt.Sprintf(body, "users"), nil)
+	require.NoError(t, err)
+	same, err := graphQLKey(g, http.MethodPost, `{"query":"query Users { users { id } }","operationName":"Users"}`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	_, err = graphQLKey(g, http.MethodPost, fmt.Sprintf(body, "add"), nil)
+	assert.Equal(t, errGraphQLNotQuery, err)
+	_, err = graphQLKey(g, http.MethodPost, fmt.Sprintf(body, "unknown"), nil)
+	assert.Equal(t, errGraphQLPersistedQuery, err)
+}
+
+func TestCacheGraphQL(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Cache(newRedisStore(1*time.Minute), 3*time.Second,
+		WithCacheStrategyByRequest((&GraphQL{}).Strategy()), WithPrefixKey("graphql_test:")))
+	engine.POST("/graphql", func(c *gin.Context) {
+		c.String(http.StatusOK, fmt.Sprintf("rand:%d", rand.Int()))
+	})
+
+	request := func(body string) string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, request(`{"query":"{ users { id } }"}`), request(`{"query":"{users{id}}"}`))
+	assert.NotEqual(t, request(`{"query":"mutation { addUser { id } }"}`), request(`{"query":"mutation { addUser { id } }"}`))
+}
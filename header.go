@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"net/http"
+	"strings"
+)
+
+// hopByHopHeaders only make sense for a single connection and are never replayed, see RFC 9110 section 7.6.1
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// defaultDeniedHeaders belong to the user who filled the cache and are not replayed unless allowed
+var defaultDeniedHeaders = []string{
+	"Set-Cookie",
+	"Set-Cookie2",
+	"Authorization",
+	"Www-Authenticate",
+	"Authentication-Info",
+}
+
+// headerFilter decides which headers of a response are stored and replayed
+type headerFilter struct {
+	allowed map[string]bool
+	denied  map[string]bool
+}
+
+func newHeaderFilter() *headerFilter {
+	f := &headerFilter{denied: map[string]bool{}}
+	for _, key := range defaultDeniedHeaders {
+		f.denied[key] = true
+	}
+	return f
+}
+
+func (f *headerFilter) allow(headers []string) {
+	if f.allowed == nil {
+		f.allowed = map[string]bool{}
+	}
+	for _, key := range headers {
+		key = http.CanonicalHeaderKey(key)
+		f.allowed[key] = true
+		delete(f.denied, key)
+	}
+}
+
+func (f *headerFilter) deny(headers []string) {
+	for _, key := range headers {
+		f.denied[http.CanonicalHeaderKey(key)] = true
+	}
+}
+
+// filter returns a copy of header without hop-by-hop, denied or not allowed headers
+func (f *headerFilter) filter(header http.Header) http.Header {
+	dropped := map[string]bool{}
+	for _, key := range hopByHopHeaders {
+		dropped[key] = true
+	}
+	// Connection lists further hop-by-hop headers
+	for _, value := range header.Values("Connection") {
+		for _, key := range strings.Split(value, ",") {
+			dropped[http.CanonicalHeaderKey(strings.TrimSpace(key))] = true
+		}
+	}
+
+	filtered := make(http.Header, len(header))
+	for key, values := range header {
+		key = http.CanonicalHeaderKey(key)
+		if dropped[key] || f.denied[key] || (f.allowed != nil && !f.allowed[key]) {
+			continue
+		}
+		filtered[key] = append(filtered[key], values...)
+	}
+	return filtered
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderFilter(t *testing.T) {
+	header := http.Header{
+		"Content-Type":      {"text/plain"},
+		"X-Multi":           {"a", "b"},
+		"Set-Cookie":        {"session=secret"},
+		"Authorization":     {"Bearer secret"},
+		"Connection":        {"X-Hop"},
+		"X-Hop":             {"1"},
+		"Transfer-Encoding": {"chunked"},
+	}
+
+	filtered := newHeaderFilter().filter(header)
+	assert.Equal(t, http.Header{"Content-Type": {"text/plain"}, "X-Multi": {"a", "b"}}, filtered)
+
+	f := newHeaderFilter()
+	f.deny([]string{"x-multi"})
+	assert.Equal(t, http.Header{"Content-Type": {"text/plain"}}, f.filter(header))
+
+	f = newHeaderFilter()
+	f.allow([]string{"set-cookie", "X-Hop"})
+	assert.Equal(t, http.Header{"Set-Cookie": {"session=secret"}}, f.filter(header))
+}
+
+func TestCacheHeaderReplay(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("header:%d:", time.Now().UnixNano())),
+	))
+	engine.GET("/header", func(c *gin.Context) {
+		c.Writer.Header().Add("X-Multi", "a")
+		c.Writer.Header().Add("X-Multi", "b")
+		c.SetCookie("session", "secret", 3600, "/", "", false, true)
+		c.String(http.StatusCreated, "value")
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/header", nil))
+		return w
+	}
+
+	w := request()
+	assert.NotEmpty(t, w.Header().Get("Set-Cookie"))
+
+	w = request()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []string{"a", "b"}, w.Header().Values("X-Multi"))
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+	assert.Equal(t, "value", w.Body.String())
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyBuilder composes a cache key from the parts of a request picked by its methods.
+// Every component is written with length prefixes, so values containing separators
+// like & or = can't make two different requests collide.
+type KeyBuilder struct {
+	components []keyComponent
+}
+
+// keyComponent writes a part of the request into the key
+type keyComponent func(c *gin.Context, w *keyWriter) error
+
+// NewKeyBuilder returns an empty KeyBuilder
+func NewKeyBuilder() *KeyBuilder {
+	return &KeyBuilder{}
+}
+
+func (b *KeyBuilder) add(component keyComponent) *KeyBuilder {
+	b.components = append(b.components, component)
+	return b
+}
+
+// Method adds the request method
+func (b *KeyBuilder) Method() *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.field("method", c.Request.Method)
+		return nil
+	})
+}
+
+// Host adds the request host
+func (b *KeyBuilder) Host() *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.field("host", strings.ToLower(c.Request.Host))
+		return nil
+	})
+}
+
+// Route adds the matched route pattern, like /user/:id
+func (b *KeyBuilder) Route() *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.field("route", c.FullPath())
+		return nil
+	})
+}
+
+// Path adds the request path
+func (b *KeyBuilder) Path() *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.field("path", c.Request.URL.Path)
+		return nil
+	})
+}
+
+// Params adds the given path parameters, or all of them if none is given
+func (b *KeyBuilder) Params(names ...string) *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.name("params")
+		if len(names) == 0 {
+			w.count(len(c.Params))
+			for _, param := range c.Params {
+				w.pair(param.Key, []string{param.Value})
+			}
+			return nil
+		}
+		for _, name := range names {
+			value, ok := c.Params.Get(name)
+			w.optionalPair(name, []string{value}, ok)
+		}
+		return nil
+	})
+}
+
+// Query adds the given query parameters, or all of them if none is given.
+// Parameters are sorted by name and their values by value, so their order doesn't matter.
+func (b *KeyBuilder) Query(names ...string) *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.name("query")
+		query := c.Request.URL.Query()
+		keys := names
+		if len(keys) == 0 {
+			for key := range query {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			w.count(len(keys))
+		}
+		for _, key := range keys {
+			values, ok := query[key]
+			values = append([]string(nil), values...)
+			sort.Strings(values)
+			w.optionalPair(key, values, ok)
+		}
+		return nil
+	})
+}
+
+// Headers adds the given request headers
+func (b *KeyBuilder) Headers(names ...string) *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.name("headers")
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			values, ok := c.Request.Header[name]
+			w.optionalPair(name, values, ok)
+		}
+		return nil
+	})
+}
+
+// Cookies adds the given request cookies
+func (b *KeyBuilder) Cookies(names ...string) *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.name("cookies")
+		for _, name := range names {
+			cookie, err := c.Request.Cookie(name)
+			if err != nil {
+				w.optionalPair(name, nil, false)
+				continue
+			}
+			w.optionalPair(name, []string{cookie.Value}, true)
+		}
+		return nil
+	})
+}
+
+// ContextValues adds the values set in the gin context with c.Set, formatted with %v
+func (b *KeyBuilder) ContextValues(keys ...string) *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		w.name("context")
+		for _, key := range keys {
+			value, ok := c.Get(key)
+			w.optionalPair(key, []string{fmt.Sprint(value)}, ok)
+		}
+		return nil
+	})
+}
+
+// BodyHash adds the SHA-256 of the request body, the body is restored for the handler.
+// Bodies larger than 1MB can't be keyed, so their requests are not cached.
+func (b *KeyBuilder) BodyHash() *KeyBuilder {
+	return b.add(func(c *gin.Context, w *keyWriter) error {
+		bodyHash, err := hashRequestBody(c.Request, defaultMaxBodySize, false)
+		if err != nil {
+			return err
+		}
+		w.field("body", bodyHash)
+		return nil
+	})
+}
+
+// Build returns the key of the request
+func (b *KeyBuilder) Build(c *gin.Context) (string, error) {
+	w := &keyWriter{}
+	for _, component := range b.components {
+		if err := component(c, w); err != nil {
+			return "", err
+		}
+	}
+	return w.String(), nil
+}
+
+// Strategy returns a GetCacheStrategyByRequest caching every request under the built key,
+// requests whose key can't be built are not cached
+func (b *KeyBuilder) Strategy() GetCacheStrategyByRequest {
+	return func(c *gin.Context) (bool, Strategy) {
+		key, err := b.Build(c)
+		if err != nil {
+			return false, Strategy{}
+		}
+		return true, Strategy{CacheKey: key}
+	}
+}
+
+// keyWriter writes length prefixed strings, like 3:GET
+type keyWriter struct {
+	strings.Builder
+}
+
+func (w *keyWriter) string(s string) {
+	w.WriteString(strconv.Itoa(len(s)))
+	w.WriteByte(':')
+	w.WriteString(s)
+}
+
+func (w *keyWriter) name(name string) {
+	w.string(name)
+}
+
+func (w *keyWriter) field(name string, value string) {
+	w.string(name)
+	w.string(value)
+}
+
+// count writes the number of items following it
+func (w *keyWriter) count(n int) {
+	w.WriteString(strconv.Itoa(n))
+	w.WriteByte('#')
+}
+
+// pair writes a name with its values, the number of values comes first
+func (w *keyWriter) pair(name string, values []string) {
+	w.string(name)
+	w.count(len(values))
+	for _, value := range values {
+		w.string(value)
+	}
+}
+
+// optionalPair writes a name with its values, or a marker telling the name is absent
+func (w *keyWriter) optionalPair(name string, values []string, ok bool) {
+	if !ok {
+		w.string(name)
+		w.WriteByte('-')
+		return
+	}
+	w.pair(name, values)
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildKey(t *testing.T, builder *KeyBuilder, method string, url string, header http.Header, body string) string {
+	var key string
+	engine := gin.New()
+	engine.Handle(method, "/user/:id", func(c *gin.Context) {
+		c.Set("tenant", "acme")
+		var err error
+		key, err = builder.Build(c)
+		require.NoError(t, err)
+	})
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	return key
+}
+
+func TestKeyBuilder(t *testing.T) {
+	builder := NewKeyBuilder().Method().Host().Route().Params().Query("a", "b").Headers("x-lang").Cookies("session").ContextValues("tenant")
+
+	key := buildKey(t, builder, http.MethodGet, "/user/1?b=2&a=1&c=3", http.Header{"X-Lang": {"en"}}, "")
+	assert.Equal(t, key, buildKey(t, builder, http.MethodGet, "/user/1?a=1&b=2&c=4", http.Header{"X-Lang": {"en"}}, ""))
+	assert.Contains(t, key, "6:tenant1#4:acme")
+
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodGet, "/user/2?a=1&b=2", http.Header{"X-Lang": {"en"}}, ""))
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodGet, "/user/1?a=1&b=2", http.Header{"X-Lang": {"fr"}}, ""))
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodGet, "/user/1?a=1&b=2", http.Header{}, ""))
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodGet, "/user/1?a=1&b=2", http.Header{"X-Lang": {"en"}, "Cookie": {"session=s1"}}, ""))
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodGet, "/user/1?a=1", http.Header{"X-Lang": {"en"}}, ""))
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodGet, "/user/1?a=1&b=", http.Header{"X-Lang": {"en"}}, ""))
+}
+
+func TestKeyBuilderUnambiguous(t *testing.T) {
+	builder := NewKeyBuilder().Headers("X-A", "X-B")
+
+	// a separator in a value can't forge another header
+	assert.NotEqual(t,
+		buildKey(t, builder, http.MethodGet, "/user/1", http.Header{"X-A": {"x&X-B=y"}}, ""),
+		buildKey(t, builder, http.MethodGet, "/user/1", http.Header{"X-A": {"x"}, "X-B": {"y"}}, ""),
+	)
+	assert.NotEqual(t,
+		buildKey(t, builder, http.MethodGet, "/user/1", http.Header{"X-A": {"x,y"}}, ""),
+		buildKey(t, builder, http.MethodGet, "/user/1", http.Header{"X-A": {"x", "y"}}, ""),
+	)
+
+	query := NewKeyBuilder().Query()
+	assert.NotEqual(t,
+		buildKey(t, query, http.MethodGet, "/user/1?a=1%26b%3D2", nil, ""),
+		buildKey(t, query, http.MethodGet, "/user/1?a=1&b=2", nil, ""),
+	)
+}
+
+func TestKeyBuilderBodyHash(t *testing.T) {
+	builder := NewKeyBuilder().Method().BodyHash()
+
+	var body string
+	engine := gin.New()
+	engine.Use(Cache(newRedisStore(0), 0, WithCacheStrategyByRequest(builder.Strategy()), WithPrefixKey("body_hash:")))
+	engine.POST("/user/:id", func(c *gin.Context) {
+		data, _ := c.GetRawData()
+		body = string(data)
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/1", strings.NewReader(`{"a":1}`)))
+	assert.Equal(t, `{"a":1}`, body)
+
+	key := buildKey(t, builder, http.MethodPost, "/user/1", nil, `{"a":1}`)
+	assert.Equal(t, key, buildKey(t, builder, http.MethodPost, "/user/1", nil, `{"a":1}`))
+	assert.NotEqual(t, key, buildKey(t, builder, http.MethodPost, "/user/1", nil, `{"a":2}`))
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,7 +22,37 @@ type Config struct {
 
 	prefixKey string
 
+	staleExpire       time.Duration
+	revalidateHandler http.Handler
+
+	staleIfErrorExpire time.Duration
+	backendTimeout     time.Duration
+
+	cacheControl bool
+
+	etag bool
+
+	vary bool
+
+	contentEncoding string
+
+	ranges bool
+
+	headerFilter *headerFilter
+
+	cacheHeaders   bool
+	debugKeyHeader string
+	debugKeySecret string
+
+	earlyExpirationBeta float64
+	expireJitter        time.Duration
+
 	headers []string
+
+	urlCanonicalizer *URLCanonicalizer
+
+	maxBodySize       int64
+	canonicalJSONBody bool
 }
 
 func newConfigByOpts(opts ...Option) *Config {
@@ -30,6 +61,9 @@ func newConfigByOpts(opts ...Option) *Config {
 		hitCacheCallback:             defaultHitCacheCallback,
 		beforeReplyWithCacheCallback: defaultBeforeReplyWithCacheCallback,
 		shareSingleFlightCallback:    defaultShareSingleFlightCallback,
+		headerFilter:                 newHeaderFilter(),
+		maxBodySize:                  defaultMaxBodySize,
+		backendTimeout:               defaultBackendTimeout,
 	}
 
 	for _, opt := range opts {
@@ -60,6 +94,32 @@ func WithHeaders(headers []string) Option {
 	}
 }
 
+// WithURLCanonicalizer rewrite request URIs with the canonicalizer before they are used as cache key,
+// only used by CacheByRequestURI
+func WithURLCanonicalizer(canonicalizer *URLCanonicalizer) Option {
+	return func(c *Config) {
+		c.urlCanonicalizer = canonicalizer
+	}
+}
+
+// WithMaxBodySize set the largest POST body hashed into the cache key by CacheByRequestURI,
+// requests with a larger body are not cached. The default is 1MB.
+func WithMaxBodySize(size int64) Option {
+	return func(c *Config) {
+		if size > 0 {
+			c.maxBodySize = size
+		}
+	}
+}
+
+// WithCanonicalJSONBody hash JSON POST bodies with sorted keys and without whitespace,
+// so that equivalent payloads share one cache entry
+func WithCanonicalJSONBody() Option {
+	return func(c *Config) {
+		c.canonicalJSONBody = true
+	}
+}
+
 // Logger define the logger interface
 type Logger interface {
 	Errorf(string, ...interface{})
@@ -139,3 +199,150 @@ func WithPrefixKey(prefix string) Option {
 		c.prefixKey = prefix
 	}
 }
+
+// WithStaleWhileRevalidate keep entries until staleExpire, or the StaleCacheDuration of the strategy.
+// Once an entry is no longer fresh, it is still served immediately while a clone of the request is
+// replayed through engine in background to refresh it, at most once per key at a time.
+// engine is usually the *gin.Engine the middleware is used in.
+func WithStaleWhileRevalidate(staleExpire time.Duration, engine http.Handler) Option {
+	return func(c *Config) {
+		if engine != nil {
+			c.staleExpire = staleExpire
+			c.revalidateHandler = engine
+		}
+	}
+}
+
+// WithStaleIfError keep entries for grace past their freshness. When the backend fails with a 5xx
+// or times out, the stale entry is served with the Warning and X-Cache: STALE headers instead.
+func WithStaleIfError(grace time.Duration) Option {
+	return func(c *Config) {
+		if grace > 0 {
+			c.staleIfErrorExpire = grace
+		}
+	}
+}
+
+// WithBackendTimeout bound the wait for the backend on a cache miss, the default is 40s.
+// Once it is reached the stale entry kept by WithStaleIfError is served, or a 500 without one,
+// to the request running the backend as well as to the ones sharing its response, unless the
+// backend already started to send it. The backend keeps running and its response is still stored.
+func WithBackendTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		if timeout > 0 {
+			c.backendTimeout = timeout
+		}
+	}
+}
+
+// WithEarlyExpiration enable the XFetch probabilistic early expiration to avoid stampedes across replicas.
+// A request close to the expiry of an entry refreshes it with a probability growing as the expiry
+// approaches, weighted by how long the response took to compute. beta scales the eagerness, 1 is a good default.
+func WithEarlyExpiration(beta float64) Option {
+	return func(c *Config) {
+		if beta > 0 {
+			c.earlyExpirationBeta = beta
+		}
+	}
+}
+
+// WithExpireJitter add a random duration in [0, jitter) to the expiration of each stored entry,
+// so that entries written together don't expire together.
+func WithExpireJitter(jitter time.Duration) Option {
+	return func(c *Config) {
+		if jitter > 0 {
+			c.expireJitter = jitter
+		}
+	}
+}
+
+// WithCacheControl honor the HTTP caching semantics of RFC 9111.
+// Responses with Cache-Control no-store, private or no-cache are not stored, s-maxage or max-age set the
+// expiration, Expires and Pragma: no-cache are used when Cache-Control is absent. Responses to requests
+// with Authorization are only stored with public, s-maxage or must-revalidate.
+// Requests with Cache-Control no-cache or max-age=0 skip the stored response, max-age=N and min-fresh
+// refuse the ones too old or about to expire, max-stale accepts expired ones still kept by the store,
+// no-store prevents storing the response, and only-if-cached gets a 504 instead of calling the backend
+// on a cache miss.
+func WithCacheControl() Option {
+	return func(c *Config) {
+		c.cacheControl = true
+	}
+}
+
+// WithETag give stored responses a strong ETag computed from the body, unless the handler set its own
+// ETag or Last-Modified. Responses served from the cache answer If-None-Match and If-Modified-Since
+// with 304 Not Modified. The response of the backend is buffered, so that it is sent with its ETag.
+func WithETag() Option {
+	return func(c *Config) {
+		c.etag = true
+	}
+}
+
+// WithVary store a variant of the entry per value of the request headers named by the Vary header
+// of the response. The names are recorded in an index next to the entry to select the variant of
+// later requests. Responses with Vary: * are not stored.
+func WithVary() Option {
+	return func(c *Config) {
+		c.vary = true
+	}
+}
+
+// WithContentEncoding store responses once, compressed with encoding (EncodingGzip or EncodingBrotli),
+// and serve them according to the Accept-Encoding of each request. Clients that don't accept the
+// stored encoding get the decompressed body. Responses already encoded by the handler are stored as is,
+// so a compression middleware must not be used after this one. With WithETag, the encoded body has
+// its own ETag, the one of the decompressed body with the encoding appended, like "<hash>-gzip".
+func WithContentEncoding(encoding string) Option {
+	return func(c *Config) {
+		if _, ok := contentEncoders[encoding]; ok {
+			c.contentEncoding = encoding
+		}
+	}
+}
+
+// WithRanges answer the Range header of requests served from the cache with 206 Partial Content,
+// multiple ranges are sent as multipart/byteranges. If-Range is validated against the stored ETag
+// or Last-Modified, and Accept-Ranges: bytes is advertised on cached responses.
+func WithRanges() Option {
+	return func(c *Config) {
+		c.ranges = true
+	}
+}
+
+// WithHeaderAllowList only store and replay the given response headers.
+// Listed headers are allowed even if denied by default, like Set-Cookie.
+func WithHeaderAllowList(headers ...string) Option {
+	return func(c *Config) {
+		if len(headers) > 0 {
+			c.headerFilter.allow(headers)
+		}
+	}
+}
+
+// WithHeaderDenyList never store and replay the given response headers, in addition to
+// Set-Cookie and the authentication headers denied by default. Hop-by-hop headers are always dropped.
+func WithHeaderDenyList(headers ...string) Option {
+	return func(c *Config) {
+		c.headerFilter.deny(headers)
+	}
+}
+
+// WithCacheHeaders add the X-Cache header, HIT, MISS, SHARED or STALE, to responses,
+// and the Age header to responses served from the cache.
+func WithCacheHeaders() Option {
+	return func(c *Config) {
+		c.cacheHeaders = true
+	}
+}
+
+// WithDebugCacheKey add the X-Cache-Key header with the cache key to the responses of requests
+// whose header carries the secret, so that keys are not exposed publicly.
+func WithDebugCacheKey(header string, secret string) Option {
+	return func(c *Config) {
+		if header != "" && secret != "" {
+			c.debugKeyHeader = header
+			c.debugKeySecret = secret
+		}
+	}
+}
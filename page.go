@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+// CachePage wraps a single handler and caches its responses, by request uri unless
+// WithCacheStrategyByRequest is given. It accepts the same options as Cache and CacheByRequestURI.
+func CachePage(
+	defaultCacheStore persistence.CacheStore,
+	defaultExpire time.Duration,
+	handle gin.HandlerFunc,
+	opts ...Option,
+) gin.HandlerFunc {
+	cfg := newConfigByOpts(opts...)
+	if cfg.getCacheStrategyByRequest == nil {
+		cfg.getCacheStrategyByRequest = requestURIStrategy(cfg)
+	}
+	return cache(defaultCacheStore, defaultExpire, cfg, handle)
+}
+
+// CachePageAtomic works like CachePage but holds a lock while the page is looked up and rendered,
+// so concurrent requests of the handler wait for the running one, whatever their cache key
+func CachePageAtomic(
+	defaultCacheStore persistence.CacheStore,
+	defaultExpire time.Duration,
+	handle gin.HandlerFunc,
+	opts ...Option,
+) gin.HandlerFunc {
+	var mu sync.Mutex
+	cachePage := CachePage(defaultCacheStore, defaultExpire, handle, opts...)
+	return func(c *gin.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		cachePage(c)
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachePage(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/page", CachePage(newRedisStore(1*time.Minute), 3*time.Second, func(c *gin.Context) {
+		c.String(http.StatusOK, fmt.Sprintf("uid:%s,rand:%d", c.Query("uid"), rand.Int()))
+	}, WithPrefixKey("cache_page:"), WithCacheHeaders()))
+	engine.GET("/other", func(c *gin.Context) {
+		c.String(http.StatusOK, fmt.Sprintf("rand:%d", rand.Int()))
+	})
+
+	request := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w1 := request("/page?uid=u1&a=1")
+	w2 := request("/page?a=1&uid=u1")
+	w3 := request("/page?uid=u2")
+	assert.Equal(t, w1.Body, w2.Body)
+	assert.Equal(t, CacheStatusMiss, w1.Header().Get("X-Cache"))
+	assert.Equal(t, CacheStatusHit, w2.Header().Get("X-Cache"))
+	assert.NotEqual(t, w1.Body, w3.Body)
+
+	// routes not wrapped are not cached
+	assert.NotEqual(t, request("/other").Body, request("/other").Body)
+}
+
+func TestCachePageWithStrategy(t *testing.T) {
+	handler := CachePage(newRedisStore(1*time.Minute), 3*time.Second, func(c *gin.Context) {
+		c.String(http.StatusOK, fmt.Sprintf("rand:%d", rand.Int()))
+	}, WithCacheStrategyByRequest(func(c *gin.Context) (bool, Strategy) {
+		return true, Strategy{CacheKey: "cache_page_strategy"}
+	}))
+
+	engine := gin.New()
+	engine.GET("/page", handler)
+
+	w1 := httptest.NewRecorder()
+	engine.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "/page?uid=u1", nil))
+	w2 := httptest.NewRecorder()
+	engine.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/page?uid=u2", nil))
+	assert.Equal(t, w1.Body, w2.Body)
+}
+
+func TestCachePageAtomic(t *testing.T) {
+	var running, maxRunning int32
+	engine := gin.New()
+	engine.GET("/page", CachePageAtomic(newRedisStore(1*time.Minute), 3*time.Second, func(c *gin.Context) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			current := atomic.LoadInt32(&maxRunning)
+			if n <= current || atomic.CompareAndSwapInt32(&maxRunning, current, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		c.String(http.StatusOK, fmt.Sprintf("uid:%s,rand:%d", c.Query("uid"), rand.Int()))
+	}, WithPrefixKey("cache_page_atomic:")))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, fmt.Sprintf("/page?uid=u%d", i%3), nil))
+		}(i)
+	}
+	wg.Wait()
+
+	// different keys are rendered one at a time
+	assert.Equal(t, int32(1), maxRunning)
+}
@@ -42,3 +42,22 @@ type CacheStore interface {
 	// Decrement decrements a real number, and returns error if the value is not real
 	Decrement(ctx context.Context, key string, data uint64) (uint64, error)
 }
+
+// TaggedStore is a CacheStore able to invalidate entries by tag
+type TaggedStore interface {
+	CacheStore
+
+	// SetWithTags sets an item to the cache like Set, and attaches the tags to it.
+	SetWithTags(ctx context.Context, key string, value interface{}, expire time.Duration, tags ...string) error
+
+	// DeleteByTag removes every item carrying one of the tags.
+	DeleteByTag(ctx context.Context, tags ...string) error
+
+	// PruneTags removes the index entries of the tags whose items are gone.
+	PruneTags(ctx context.Context, tags ...string) error
+}
+
+// OnCorruptionCallback define the callback when a stored entry fails the integrity check
+type OnCorruptionCallback func(ctx context.Context, key string, err error)
+
+var defaultCorruptionCallback = func(ctx context.Context, key string, err error) {}
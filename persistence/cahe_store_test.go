@@ -188,3 +188,53 @@ func testAdd(t *testing.T, newCache cacheFactory) {
 		t.Errorf("Expected 3, got: %d", i)
 	}
 }
+
+// tagMembers returns the number of keys indexed under the tag of the store
+type tagMembers func(t *testing.T, cache TaggedStore, tag string) int
+
+func testTags(t *testing.T, newCache cacheFactory, members tagMembers) {
+	ctx := context.TODO()
+	cache := newCache(t, time.Hour).(TaggedStore)
+
+	if err := cache.SetWithTags(ctx, "user42", "u", DEFAULT, "user:42", "catalog"); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+	if err := cache.SetWithTags(ctx, "product1", "p", DEFAULT, "catalog"); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+	if err := cache.SetWithTags(ctx, "user43", "u", DEFAULT, "user:43"); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+
+	if err := cache.DeleteByTag(ctx, "catalog"); err != nil {
+		t.Errorf("Error deleting by tag: %s", err)
+	}
+	var value string
+	for _, key := range []string{"user42", "product1"} {
+		if err := cache.Get(ctx, key, &value); err != ErrCacheMiss {
+			t.Errorf("Expected %s to be deleted, got: %v", key, err)
+		}
+	}
+	if err := cache.Get(ctx, "user43", &value); err != nil {
+		t.Errorf("Expected untagged entries to be kept, got: %s", err)
+	}
+
+	if n := members(t, cache, "catalog"); n != 0 {
+		t.Errorf("Expected the catalog index to be deleted, has %d members", n)
+	}
+
+	// an entry deleted on its own may leave a dangling index member, removed by PruneTags
+	if err := cache.SetWithTags(ctx, "user44", "u", DEFAULT, "user:43"); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+	_ = cache.Delete(ctx, "user43")
+	if err := cache.PruneTags(ctx, "user:43"); err != nil {
+		t.Errorf("Error pruning tags: %s", err)
+	}
+	if n := members(t, cache, "user:43"); n != 1 {
+		t.Errorf("Expected only user44 to be left in the index, has %d members", n)
+	}
+	if err := cache.DeleteByTag(ctx, "user:42", "user:43"); err != nil {
+		t.Errorf("Error deleting by tag: %s", err)
+	}
+}
@@ -0,0 +1,151 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound should be returned by a Loader when the value doesn't exist,
+// it is cached with its own expiration when negative caching is enabled
+var ErrNotFound = errors.New("cache: not found")
+
+// Loader loads the value of key when it is not in the cache
+type Loader[T any] func(ctx context.Context, key string) (T, error)
+
+// loadedEntry is what LoadingCache keeps in the store, Found is false for cached ErrNotFound
+type loadedEntry[T any] struct {
+	Found bool
+	Value T
+}
+
+// LoadingCache is a read-through cache, concurrent loads of the same key are collapsed into one
+type LoadingCache[T any] struct {
+	store          CacheStore
+	loader         Loader[T]
+	expire         time.Duration
+	negativeExpire time.Duration
+	loadTimeout    time.Duration
+	group          singleflight.Group
+}
+
+// LoadingOption represents the optional function of LoadingCache
+type LoadingOption func(c *loadingConfig)
+
+type loadingConfig struct {
+	negativeExpire time.Duration
+	loadTimeout    time.Duration
+}
+
+// defaultLoadTimeout bounds a shared load, which no longer follows the context of its callers
+const defaultLoadTimeout = time.Minute
+
+// WithNegativeExpire cache ErrNotFound returned by the loader for the given duration
+func WithNegativeExpire(expire time.Duration) LoadingOption {
+	return func(c *loadingConfig) {
+		if expire > 0 {
+			c.negativeExpire = expire
+		}
+	}
+}
+
+// WithLoadTimeout bound the time a load may take, one minute by default.
+// A load is shared by every caller of the key, so it isn't canceled with the context of any of them.
+func WithLoadTimeout(timeout time.Duration) LoadingOption {
+	return func(c *loadingConfig) {
+		if timeout > 0 {
+			c.loadTimeout = timeout
+		}
+	}
+}
+
+// NewLoadingCache returns a LoadingCache storing loaded values in store for expire
+func NewLoadingCache[T any](store CacheStore, expire time.Duration, loader Loader[T], opts ...LoadingOption) *LoadingCache[T] {
+	cfg := &loadingConfig{loadTimeout: defaultLoadTimeout}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &LoadingCache[T]{
+		store:          store,
+		loader:         loader,
+		expire:         expire,
+		negativeExpire: cfg.negativeExpire,
+		loadTimeout:    cfg.loadTimeout,
+	}
+}
+
+// Get retrieves the value of key, loading it on a cache miss.
+// ErrNotFound is returned for values the loader couldn't find, other load errors are returned as is
+// and are never cached.
+func (c *LoadingCache[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+
+	entry := &loadedEntry[T]{}
+	err := c.store.Get(ctx, key, entry)
+	if err == nil {
+		if !entry.Found {
+			return zero, ErrNotFound
+		}
+		return entry.Value, nil
+	}
+	if err != ErrCacheMiss {
+		return zero, err
+	}
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		// the first caller going away must not fail the load of the others
+		ctx, cancel := context.WithTimeout(detachedContext{ctx}, c.loadTimeout)
+		defer cancel()
+
+		value, err := c.loader(ctx, key)
+		if err == ErrNotFound {
+			if c.negativeExpire > 0 {
+				_ = c.store.Set(ctx, key, &loadedEntry[T]{}, c.negativeExpire)
+			}
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		_ = c.store.Set(ctx, key, &loadedEntry[T]{Found: true, Value: value}, c.expire)
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case ret := <-ch:
+		if ret.Err != nil {
+			return zero, ret.Err
+		}
+		return ret.Val.(T), nil
+	}
+}
+
+// detachedContext keeps the values of its parent, but not its deadline and cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Invalidate removes the cached value of key
+func (c *LoadingCache[T]) Invalidate(ctx context.Context, key string) error {
+	err := c.store.Delete(ctx, key)
+	if err == ErrCacheMiss {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCache_CollapseLoads(t *testing.T) {
+	ctx := context.TODO()
+	store := newRedisStore(t, time.Hour)
+	_ = store.Delete(ctx, "loading")
+
+	var loads int32
+	cache := NewLoadingCache(store, DEFAULT, func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "value of " + key, nil
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.Get(ctx, "loading")
+			if err != nil || value != "value of loading" {
+				t.Errorf("Expected the loaded value, got %s, %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := cache.Get(ctx, "loading"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load, was %d", loads)
+	}
+}
+
+func TestLoadingCache_NegativeCaching(t *testing.T) {
+	ctx := context.TODO()
+	store := newRedisStore(t, time.Hour)
+	_ = store.Delete(ctx, "loading_not_found")
+	_ = store.Delete(ctx, "loading_err")
+
+	loads := 0
+	errLoad := errors.New("load failed")
+	cache := NewLoadingCache(store, DEFAULT, func(ctx context.Context, key string) (int, error) {
+		loads++
+		if key == "loading_err" {
+			return 0, errLoad
+		}
+		return 0, ErrNotFound
+	}, WithNegativeExpire(time.Second))
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(ctx, "loading_not_found"); err != ErrNotFound {
+			t.Errorf("Expected ErrNotFound, got: %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected not found to be cached, loaded %d times", loads)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, err := cache.Get(ctx, "loading_not_found"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+	if loads != 2 {
+		t.Errorf("Expected not found to expire, loaded %d times", loads)
+	}
+
+	loads = 0
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(ctx, "loading_err"); err != errLoad {
+			t.Errorf("Expected the loader error, got: %v", err)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("Expected load errors not to be cached, loaded %d times", loads)
+	}
+}
+
+func TestLoadingCache_FirstCallerCanceled(t *testing.T) {
+	store := newRedisStore(t, time.Hour)
+	_ = store.Delete(context.TODO(), "loading_canceled")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cache := NewLoadingCache(store, DEFAULT, func(ctx context.Context, key string) (string, error) {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		return "value of " + key, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cache.Get(ctx, "loading_canceled")
+		firstErr <- err
+	}()
+	<-started
+
+	secondValue := make(chan string)
+	go func() {
+		value, err := cache.Get(context.TODO(), "loading_canceled")
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		secondValue <- value
+	}()
+
+	// the first caller gives up while the load is running
+	cancel()
+	if err := <-firstErr; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	close(release)
+
+	if value := <-secondValue; value != "value of loading_canceled" {
+		t.Errorf("Expected the loaded value, got %s", value)
+	}
+	var entry loadedEntry[string]
+	if err := store.Get(context.TODO(), "loading_canceled", &entry); err != nil || entry.Value != "value of loading_canceled" {
+		t.Errorf("Expected the value to be cached, got %v, %v", entry, err)
+	}
+}
@@ -0,0 +1,319 @@
+package persistence
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cache/utils"
+)
+
+// defaultCleanupInterval how often expired items are removed by default
+const defaultCleanupInterval = time.Minute
+
+// InMemoryStore represents the cache with memory persistence
+type InMemoryStore struct {
+	mu                sync.RWMutex
+	items             map[string]memoryItem
+	tags              map[string]map[string]struct{}
+	defaultExpiration time.Duration
+	codec             utils.Codec
+	cleanupInterval   time.Duration
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type memoryItem struct {
+	data      []byte
+	expiresAt time.Time
+	// tags the item is indexed under
+	tags []string
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && now.After(i.expiresAt)
+}
+
+var _ TaggedStore = (*InMemoryStore)(nil)
+
+// MemoryOption represents the optional function of InMemoryStore
+type MemoryOption func(c *InMemoryStore)
+
+// WithMemoryCodec set the codec used to encode values, gob is used by default
+func WithMemoryCodec(codec utils.Codec) MemoryOption {
+	return func(c *InMemoryStore) {
+		if codec != nil {
+			c.codec = codec
+		}
+	}
+}
+
+// WithCleanupInterval set how often expired items are removed in background, every minute by default.
+// No cleanup goroutine is started if interval isn't positive, expired items are then only removed
+// when they are accessed or by DeleteExpired.
+func WithCleanupInterval(interval time.Duration) MemoryOption {
+	return func(c *InMemoryStore) {
+		c.cleanupInterval = interval
+	}
+}
+
+// NewInMemoryStore returns a InMemoryStore, Close must be called to stop its cleanup goroutine
+func NewInMemoryStore(defaultExpiration time.Duration, opts ...MemoryOption) *InMemoryStore {
+	c := &InMemoryStore{
+		items:             map[string]memoryItem{},
+		tags:              map[string]map[string]struct{}{},
+		defaultExpiration: defaultExpiration,
+		codec:             utils.GobCodec,
+		cleanupInterval:   defaultCleanupInterval,
+		stop:              make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.cleanupInterval > 0 {
+		go c.cleanup(c.cleanupInterval)
+	}
+	return c
+}
+
+func (c *InMemoryStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.DeleteExpired()
+		}
+	}
+}
+
+// Close stops the cleanup goroutine, the store stays usable
+func (c *InMemoryStore) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// Get (see CacheStore interface)
+func (c *InMemoryStore) Get(ctx context.Context, key string, value interface{}) error {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok {
+		return ErrCacheMiss
+	}
+	if now := time.Now(); item.expired(now) {
+		c.mu.Lock()
+		if item, ok := c.items[key]; ok && item.expired(now) {
+			c.removeLocked(key)
+		}
+		c.mu.Unlock()
+		return ErrCacheMiss
+	}
+
+	err := utils.Open(item.data, value)
+	if err == utils.ErrUnknownEnvelope || err == utils.ErrEnvelopeExpired {
+		return ErrCacheMiss
+	}
+	return err
+}
+
+// Set (see CacheStore interface)
+func (c *InMemoryStore) Set(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	item, err := c.newItem(value, expires)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.storeLocked(key, item)
+	c.mu.Unlock()
+	return nil
+}
+
+// Add (see CacheStore interface)
+func (c *InMemoryStore) Add(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	item, err := c.newItem(value, expires)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.items[key]; ok && !old.expired(time.Now()) {
+		return ErrNotStored
+	}
+	c.storeLocked(key, item)
+	return nil
+}
+
+// Replace (see CacheStore interface)
+func (c *InMemoryStore) Replace(ctx context.Context, key string, value interface{}, expires time.Duration) error {
+	if value == nil {
+		return ErrNotStored
+	}
+	item, err := c.newItem(value, expires)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.items[key]; !ok || old.expired(time.Now()) {
+		return ErrNotStored
+	}
+	c.storeLocked(key, item)
+	return nil
+}
+
+// Delete (see CacheStore interface)
+func (c *InMemoryStore) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	c.removeLocked(key)
+	if !ok || item.expired(time.Now()) {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// Increment (see CacheStore interface)
+func (c *InMemoryStore) Increment(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.add(key, func(current uint64) uint64 {
+		return current + delta
+	})
+}
+
+// Decrement (see CacheStore interface)
+func (c *InMemoryStore) Decrement(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.add(key, func(current uint64) uint64 {
+		// Decrement contract says you can only go to 0
+		if delta > current {
+			return 0
+		}
+		return current - delta
+	})
+}
+
+func (c *InMemoryStore) add(key string, f func(current uint64) uint64) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok || item.expired(time.Now()) {
+		return 0, ErrCacheMiss
+	}
+	current, err := strconv.ParseUint(string(item.data), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	newValue := f(current)
+	item.data = []byte(strconv.FormatUint(newValue, 10))
+	c.items[key] = item
+	return newValue, nil
+}
+
+// SetWithTags (see TaggedStore interface)
+func (c *InMemoryStore) SetWithTags(ctx context.Context, key string, value interface{}, expires time.Duration, tags ...string) error {
+	item, err := c.newItem(value, expires)
+	if err != nil {
+		return err
+	}
+	item.tags = tags
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeLocked(key, item)
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+// DeleteByTag (see TaggedStore interface)
+func (c *InMemoryStore) DeleteByTag(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.removeLocked(key)
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+// PruneTags (see TaggedStore interface)
+func (c *InMemoryStore) PruneTags(ctx context.Context, tags ...string) error {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if item, ok := c.items[key]; !ok || item.expired(now) {
+				delete(c.tags[tag], key)
+			}
+		}
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	return nil
+}
+
+// DeleteExpired removes all expired items, it is run by the cleanup goroutine
+func (c *InMemoryStore) DeleteExpired() {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, item := range c.items {
+		if item.expired(now) {
+			c.removeLocked(key)
+		}
+	}
+}
+
+// storeLocked stores the item under key, the key is removed from the tags of the item it replaces
+func (c *InMemoryStore) storeLocked(key string, item memoryItem) {
+	c.unindexLocked(key)
+	c.items[key] = item
+}
+
+// removeLocked deletes the item and its entries in the tag index
+func (c *InMemoryStore) removeLocked(key string) {
+	c.unindexLocked(key)
+	delete(c.items, key)
+}
+
+func (c *InMemoryStore) unindexLocked(key string) {
+	for _, tag := range c.items[key].tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+func (c *InMemoryStore) newItem(value interface{}, expires time.Duration) (memoryItem, error) {
+	switch expires {
+	case DEFAULT:
+		expires = c.defaultExpiration
+	case FOREVER:
+		expires = time.Duration(0)
+	}
+
+	b, err := utils.Seal(c.codec, value, expires)
+	if err != nil {
+		return memoryItem{}, err
+	}
+	item := memoryItem{data: b}
+	if expires > 0 {
+		item.expiresAt = time.Now().Add(expires)
+	}
+	return item, nil
+}
@@ -0,0 +1,98 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/cache/utils"
+)
+
+var newInMemoryStore = func(t *testing.T, defaultExpiration time.Duration) CacheStore {
+	c := NewInMemoryStore(defaultExpiration)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func inMemoryTagMembers(_ *testing.T, cache TaggedStore, tag string) int {
+	c := cache.(*InMemoryStore)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.tags[tag])
+}
+
+func TestInMemoryCache_TypicalGetSet(t *testing.T) {
+	typicalGetSet(t, newInMemoryStore)
+}
+
+func TestInMemoryCache_IncrDecr(t *testing.T) {
+	incrDecr(t, newInMemoryStore)
+}
+
+func TestInMemoryCache_Expiration(t *testing.T) {
+	expiration(t, newInMemoryStore)
+}
+
+func TestInMemoryCache_EmptyCache(t *testing.T) {
+	emptyCache(t, newInMemoryStore)
+}
+
+func TestInMemoryCache_Replace(t *testing.T) {
+	testReplace(t, newInMemoryStore)
+}
+
+func TestInMemoryCache_Add(t *testing.T) {
+	testAdd(t, newInMemoryStore)
+}
+
+func TestInMemoryCache_Tags(t *testing.T) {
+	testTags(t, newInMemoryStore, inMemoryTagMembers)
+}
+
+func TestInMemoryCache_Codec(t *testing.T) {
+	c := NewInMemoryStore(time.Hour, WithMemoryCodec(utils.JSONCodec))
+	defer c.Close()
+	typicalGetSet(t, func(*testing.T, time.Duration) CacheStore { return c })
+
+	var e utils.Envelope
+	if err := e.UnmarshalBinary(c.items["value"].data); err != nil || e.CodecID != utils.JSONCodec.ID() {
+		t.Errorf("Expected a json envelope, got codec %d, %v", e.CodecID, err)
+	}
+}
+
+func TestInMemoryCache_TagIndex(t *testing.T) {
+	ctx := context.TODO()
+	c := NewInMemoryStore(time.Hour)
+	defer c.Close()
+
+	_ = c.SetWithTags(ctx, "user42", "u", DEFAULT, "user:42", "users")
+	_ = c.Set(ctx, "user42", "u", DEFAULT)
+	if len(c.tags) != 0 {
+		t.Errorf("Expected an untagged replacement to leave the index, got %v", c.tags)
+	}
+
+	_ = c.SetWithTags(ctx, "user43", "u", DEFAULT, "users")
+	_ = c.Delete(ctx, "user43")
+	if len(c.tags) != 0 {
+		t.Errorf("Expected a deleted entry to leave the index, got %v", c.tags)
+	}
+}
+
+func TestInMemoryCache_Cleanup(t *testing.T) {
+	ctx := context.TODO()
+	c := NewInMemoryStore(time.Hour, WithCleanupInterval(10*time.Millisecond))
+	defer c.Close()
+
+	_ = c.SetWithTags(ctx, "short", "v", 20*time.Millisecond, "tag")
+	_ = c.Set(ctx, "long", "v", DEFAULT)
+	time.Sleep(100 * time.Millisecond)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.items["short"]; ok || len(c.tags) != 0 {
+		t.Errorf("Expected the expired item to be removed in background")
+	}
+	if _, ok := c.items["long"]; !ok {
+		t.Errorf("Expected the fresh item to be kept")
+	}
+}
@@ -0,0 +1,61 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Namespace groups keys under a generation counter kept in the store.
+// Keys built by the namespace embed the current generation, so bumping the counter
+// with Invalidate orphans every entry of the namespace at once, they age out through their TTLs.
+type Namespace struct {
+	store CacheStore
+	name  string
+}
+
+// NewNamespace returns the namespace name backed by store
+func NewNamespace(store CacheStore, name string) *Namespace {
+	return &Namespace{store: store, name: name}
+}
+
+func (n *Namespace) generationKey() string {
+	return "ns:" + n.name + ":generation"
+}
+
+// Generation returns the current generation of the namespace
+func (n *Namespace) Generation(ctx context.Context) (uint64, error) {
+	var generation uint64
+	err := n.store.Get(ctx, n.generationKey(), &generation)
+	if err != ErrCacheMiss {
+		return generation, err
+	}
+
+	// start from the clock rather than zero, if the counter is ever evicted
+	// the namespace must not come back to a generation that was already used
+	generation = uint64(time.Now().UnixNano())
+	err = n.store.Add(ctx, n.generationKey(), generation, FOREVER)
+	if err == ErrNotStored {
+		// initialized concurrently
+		err = n.store.Get(ctx, n.generationKey(), &generation)
+	}
+	return generation, err
+}
+
+// Key returns key in the current generation of the namespace
+func (n *Namespace) Key(ctx context.Context, key string) (string, error) {
+	generation, err := n.Generation(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ns:%s:%d:%s", n.name, generation, key), nil
+}
+
+// Invalidate bumps the generation of the namespace
+func (n *Namespace) Invalidate(ctx context.Context) error {
+	_, err := n.store.Increment(ctx, n.generationKey(), 1)
+	if err == ErrCacheMiss {
+		_, err = n.Generation(ctx)
+	}
+	return err
+}
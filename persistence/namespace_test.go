@@ -0,0 +1,47 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNamespace_Invalidate(t *testing.T) {
+	ctx := context.TODO()
+	store := newRedisStore(t, time.Hour)
+	products := NewNamespace(store, "/products")
+	users := NewNamespace(store, "/users")
+
+	productKey, err := products.Key(ctx, "1")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	userKey, err := users.Key(ctx, "1")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if productKey == userKey {
+		t.Errorf("Expected keys of different namespaces to differ, got %s", productKey)
+	}
+	_ = store.Set(ctx, productKey, "product", DEFAULT)
+
+	if key, _ := products.Key(ctx, "1"); key != productKey {
+		t.Errorf("Expected a stable key %s, got %s", productKey, key)
+	}
+
+	if err = products.Invalidate(ctx); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	key, _ := products.Key(ctx, "1")
+	if key == productKey {
+		t.Errorf("Expected the key to change after invalidation")
+	}
+	var value string
+	if err = store.Get(ctx, key, &value); err != ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss after invalidation, got: %v", err)
+	}
+
+	if key, _ = users.Key(ctx, "1"); key != userKey {
+		t.Errorf("Expected other namespaces to be untouched, got %s", key)
+	}
+}
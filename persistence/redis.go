@@ -15,12 +15,58 @@ type RedisStore struct {
 	defaultExpiration time.Duration
 	prefix            string
 	ctx               context.Context
+	codec             utils.Codec
+	signer            *utils.Signer
+	onCorruption      OnCorruptionCallback
+}
+
+// RedisOption represents the optional function of RedisStore
+type RedisOption func(c *RedisStore)
+
+// WithCodec set the codec used to encode values, gob is used by default
+func WithCodec(codec utils.Codec) RedisOption {
+	return func(c *RedisStore) {
+		if codec != nil {
+			c.codec = codec
+		}
+	}
+}
+
+// WithSigner sign every stored envelope with an HMAC of the key and value, entries
+// failing the verification are reported to the corruption callback and treated as a miss.
+// Integers are stored bare so that Increment and Decrement work, counters are therefore not signed.
+func WithSigner(secret []byte) RedisOption {
+	return func(c *RedisStore) {
+		if len(secret) > 0 {
+			c.signer = utils.NewSigner(secret)
+		}
+	}
+}
+
+// WithOnCorruption will be called when a stored entry fails the signature verification
+func WithOnCorruption(cb OnCorruptionCallback) RedisOption {
+	return func(c *RedisStore) {
+		if cb != nil {
+			c.onCorruption = cb
+		}
+	}
 }
 
 // NewRedisCache returns a RedisStore
 // until redigo supports sharding/clustering, only one host will be in hostList
-func NewRedisCache(pool *redis.Pool, defaultExpiration time.Duration, prefix string) *RedisStore {
-	return &RedisStore{pool, defaultExpiration, prefix, context.TODO()}
+func NewRedisCache(pool *redis.Pool, defaultExpiration time.Duration, prefix string, opts ...RedisOption) *RedisStore {
+	c := &RedisStore{
+		pool:              pool,
+		defaultExpiration: defaultExpiration,
+		prefix:            prefix,
+		ctx:               context.TODO(),
+		codec:             utils.GobCodec,
+		onCorruption:      defaultCorruptionCallback,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Set (see CacheStore interface)
@@ -75,7 +121,26 @@ func (c *RedisStore) Get(ctx context.Context, key string, ptrValue interface{})
 	if err != nil {
 		return err
 	}
-	return utils.Deserialize(item, ptrValue)
+	if c.signer != nil {
+		switch {
+		case utils.IsEnvelope(item):
+			item, err = c.signer.Verify(c.KeyWithPrefix(key), item)
+			if err != nil {
+				c.onCorruption(ctx, key, err)
+				return ErrCacheMiss
+			}
+		case !utils.IsIntegerPtr(ptrValue):
+			// only counters are stored without an envelope, anything else is damaged or forged
+			c.onCorruption(ctx, key, utils.ErrSignatureMismatch)
+			return ErrCacheMiss
+		}
+	}
+	err = utils.Open(item, ptrValue)
+	if err == utils.ErrUnknownEnvelope || err == utils.ErrEnvelopeExpired {
+		// written by an incompatible version, don't serve it
+		return ErrCacheMiss
+	}
+	return err
 }
 
 func exists(ctx context.Context, conn redis.Conn, key string) bool {
@@ -149,17 +214,15 @@ func (c *RedisStore) Decrement(ctx context.Context, key string, delta uint64) (n
 func (c *RedisStore) invoke(ctx context.Context, f func(string, ...interface{}) (interface{}, error),
 	key string, value interface{}, expires time.Duration) error {
 
-	switch expires {
-	case DEFAULT:
-		expires = c.defaultExpiration
-	case FOREVER:
-		expires = time.Duration(0)
-	}
+	expires = c.expiration(expires)
 
-	b, err := utils.Serialize(value)
+	b, err := utils.Seal(c.codec, value, expires)
 	if err != nil {
 		return err
 	}
+	if c.signer != nil && utils.IsEnvelope(b) {
+		b = c.signer.Sign(key, b)
+	}
 
 	if expires > 0 {
 		_, err := f("SETEX", key, int32(expires/time.Second), b, ctx)
@@ -171,6 +234,17 @@ func (c *RedisStore) invoke(ctx context.Context, f func(string, ...interface{})
 
 }
 
+// expiration resolves DEFAULT and FOREVER, zero means the key never expires
+func (c *RedisStore) expiration(expires time.Duration) time.Duration {
+	switch expires {
+	case DEFAULT:
+		return c.defaultExpiration
+	case FOREVER:
+		return time.Duration(0)
+	}
+	return expires
+}
+
 func (c *RedisStore) KeyWithPrefix(key string) string {
 	if c.prefix != "" {
 		return fmt.Sprintf("%s:%s", c.prefix, key)
@@ -0,0 +1,138 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// scanBatchSize is the COUNT hint passed to SCAN
+const scanBatchSize = 500
+
+// KeyIterator walks the keys matched by RedisStore.Scan, a batch is fetched with SCAN
+// whenever the previous one is consumed, so the keyspace is never loaded at once.
+// Keys created or deleted during the iteration may or may not be returned.
+type KeyIterator struct {
+	store   *RedisStore
+	ctx     context.Context
+	match   string
+	cursor  string
+	started bool
+	batch   []string
+	key     string
+	err     error
+}
+
+// Scan returns an iterator over the keys matching the glob-style pattern.
+// The pattern and the returned keys don't include the store's prefix.
+func (c *RedisStore) Scan(ctx context.Context, pattern string) *KeyIterator {
+	return &KeyIterator{
+		store:  c,
+		ctx:    ctx,
+		match:  c.patternWithPrefix(pattern),
+		cursor: "0",
+	}
+}
+
+// Next advances the iterator, it returns false when the iteration is done or failed
+func (it *KeyIterator) Next() bool {
+	for len(it.batch) == 0 {
+		if it.err != nil || (it.started && it.cursor == "0") {
+			return false
+		}
+		it.batch, it.err = it.store.scan(it.ctx, &it.cursor, it.match)
+		it.started = true
+	}
+	it.key, it.batch = it.batch[0], it.batch[1:]
+	return true
+}
+
+// Key returns the current key
+func (it *KeyIterator) Key() string {
+	return it.store.trimPrefix(it.key)
+}
+
+// Err returns the error that stopped the iteration
+func (it *KeyIterator) Err() error {
+	return it.err
+}
+
+// DeleteByPattern removes all keys matching the glob-style pattern with UNLINK, one SCAN batch at a time.
+// The pattern doesn't include the store's prefix. It returns the number of removed keys.
+func (c *RedisStore) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	match := c.patternWithPrefix(pattern)
+	cursor := "0"
+	deleted := 0
+	for {
+		keys, err := c.scan(ctx, &cursor, match)
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			n, err := c.unlink(ctx, keys)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+		if cursor == "0" {
+			return deleted, nil
+		}
+	}
+}
+
+func (c *RedisStore) scan(ctx context.Context, cursor *string, match string) ([]string, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do("SCAN", *cursor, "MATCH", match, "COUNT", scanBatchSize, ctx))
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	if _, err = redis.Scan(reply, cursor, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *RedisStore) unlink(ctx context.Context, keys []string) (int, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	args := make([]interface{}, 0, len(keys)+1)
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	return redis.Int(conn.Do("UNLINK", append(args, ctx)...))
+}
+
+// patternWithPrefix prefixes the pattern with the store's prefix, escaping the glob characters of the prefix
+func (c *RedisStore) patternWithPrefix(pattern string) string {
+	if c.prefix == "" {
+		return pattern
+	}
+	var b strings.Builder
+	for _, r := range c.prefix {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String() + ":" + pattern
+}
+
+func (c *RedisStore) trimPrefix(key string) string {
+	if c.prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, c.prefix+":")
+}
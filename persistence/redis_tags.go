@@ -0,0 +1,160 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// tagBatchSize is the number of index members handled per round trip
+const tagBatchSize = 500
+
+var _ TaggedStore = (*RedisStore)(nil)
+
+// tagKey returns the key of the index set of tag. Item keys are "<prefix>:<key>", the NUL byte keeps
+// index sets apart from them, so that an item named like tag:x can't clobber an index.
+func (c *RedisStore) tagKey(tag string) string {
+	return c.prefix + "\x00tag:" + tag
+}
+
+// SetWithTags (see TaggedStore interface)
+// Each tag is a redis set of keys, its expiry is extended to cover the longest lived item.
+// The set lives forever once an item tagged with it does.
+func (c *RedisStore) SetWithTags(ctx context.Context, key string, value interface{}, expires time.Duration, tags ...string) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = c.invoke(ctx, conn.Do, c.KeyWithPrefix(key), value, expires); err != nil {
+		return err
+	}
+
+	expires = c.expiration(expires)
+	for _, tag := range tags {
+		if err = c.addToTag(ctx, conn, c.tagKey(tag), c.KeyWithPrefix(key), expires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addToTag adds the item key to the index set and extends the expiry of the set to cover the item
+func (c *RedisStore) addToTag(ctx context.Context, conn redis.Conn, tagKey string, key string, expires time.Duration) error {
+	// read before SADD, which creates the set without expiry: -1 then means it was persisted
+	// for an item living forever, -2 that it doesn't exist yet
+	ttl, err := redis.Int64(conn.Do("PTTL", tagKey, ctx))
+	if err != nil {
+		return err
+	}
+	if _, err = conn.Do("SADD", tagKey, key, ctx); err != nil {
+		return err
+	}
+
+	if expires <= 0 {
+		_, err = conn.Do("PERSIST", tagKey, ctx)
+		return err
+	}
+	milliseconds := int64(expires / time.Millisecond)
+	if ttl == -1 || ttl >= milliseconds {
+		return nil
+	}
+	_, err = conn.Do("PEXPIRE", tagKey, milliseconds, ctx)
+	return err
+}
+
+// DeleteByTag (see TaggedStore interface)
+func (c *RedisStore) DeleteByTag(ctx context.Context, tags ...string) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+		err = c.scanTag(ctx, conn, tagKey, func(keys []interface{}) error {
+			_, err := conn.Do("UNLINK", append(keys, ctx)...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = conn.Do("UNLINK", tagKey, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneTags (see TaggedStore interface)
+func (c *RedisStore) PruneTags(ctx context.Context, tags ...string) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+		err = c.scanTag(ctx, conn, tagKey, func(keys []interface{}) error {
+			// pipeline the EXISTS of the batch into a single round trip
+			for _, key := range keys {
+				if err := conn.Send("EXISTS", key); err != nil {
+					return err
+				}
+			}
+			if err := conn.Flush(); err != nil {
+				return err
+			}
+			dangling := []interface{}{tagKey}
+			for _, key := range keys {
+				found, err := redis.Bool(conn.Receive())
+				if err != nil {
+					return err
+				}
+				if !found {
+					dangling = append(dangling, key)
+				}
+			}
+			if len(dangling) == 1 {
+				return nil
+			}
+			_, err := conn.Do("SREM", append(dangling, ctx)...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanTag walks the members of the tag set with SSCAN and hands them to fn in batches
+func (c *RedisStore) scanTag(ctx context.Context, conn redis.Conn, tagKey string, fn func(keys []interface{}) error) error {
+	cursor := "0"
+	for {
+		reply, err := redis.Values(conn.Do("SSCAN", tagKey, cursor, "COUNT", tagBatchSize, ctx))
+		if err != nil {
+			return err
+		}
+		var members []string
+		if _, err = redis.Scan(reply, &cursor, &members); err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			keys := make([]interface{}, 0, len(members))
+			for _, member := range members {
+				keys = append(keys, member)
+			}
+			if err = fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package persistence
 
 import (
+	"context"
+	"fmt"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/gin-contrib/cache/utils"
 	"github.com/gomodule/redigo/redis"
 	redigotrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/gomodule/redigo"
 )
@@ -13,6 +16,16 @@ import (
 const redisTestServer = "localhost:6379"
 
 var newRedisStore = func(t *testing.T, defaultExpiration time.Duration) CacheStore {
+	return newRedisStoreWithOptions()(t, defaultExpiration)
+}
+
+func newRedisStoreWithOptions(opts ...RedisOption) cacheFactory {
+	return func(t *testing.T, defaultExpiration time.Duration) CacheStore {
+		return dialRedisStore(t, defaultExpiration, opts...)
+	}
+}
+
+func dialRedisStore(t *testing.T, defaultExpiration time.Duration, opts ...RedisOption) CacheStore {
 	c, err := net.Dial("tcp", redisTestServer)
 	if err == nil {
 		_, _ = c.Write([]byte("flush_all\r\n"))
@@ -25,7 +38,7 @@ var newRedisStore = func(t *testing.T, defaultExpiration time.Duration) CacheSto
 			}
 			return c, err
 		}}
-		redisCache := NewRedisCache(pool, defaultExpiration, "")
+		redisCache := NewRedisCache(pool, defaultExpiration, "", opts...)
 		return redisCache
 	}
 	t.Errorf("couldn't connect to redis on %s", redisTestServer)
@@ -56,3 +69,169 @@ func TestRedisCache_Replace(t *testing.T) {
 func TestRedisCache_Add(t *testing.T) {
 	testAdd(t, newRedisStore)
 }
+
+func TestRedisCache_JSONCodec(t *testing.T) {
+	typicalGetSet(t, newRedisStoreWithOptions(WithCodec(utils.JSONCodec)))
+	incrDecr(t, newRedisStoreWithOptions(WithCodec(utils.JSONCodec)))
+}
+
+func TestRedisCache_MsgpackCodec(t *testing.T) {
+	typicalGetSet(t, newRedisStoreWithOptions(WithCodec(utils.MsgpackCodec)))
+	incrDecr(t, newRedisStoreWithOptions(WithCodec(utils.MsgpackCodec)))
+}
+
+func TestRedisCache_Signer(t *testing.T) {
+	ctx := context.TODO()
+	var corrupted []string
+	onCorruption := func(ctx context.Context, key string, err error) {
+		corrupted = append(corrupted, key)
+	}
+	signed := dialRedisStore(t, time.Hour, WithSigner([]byte("secret")), WithOnCorruption(onCorruption))
+	rogue := dialRedisStore(t, time.Hour, WithSigner([]byte("another secret")))
+
+	if err := signed.Set(ctx, "signed", "foo", DEFAULT); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+	var value string
+	if err := signed.Get(ctx, "signed", &value); err != nil || value != "foo" {
+		t.Errorf("Expected to get foo back, got %s, %v", value, err)
+	}
+
+	if err := rogue.Set(ctx, "signed", "bar", DEFAULT); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+	if err := signed.Get(ctx, "signed", &value); err != ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss for a forged entry, got: %v", err)
+	}
+	if len(corrupted) != 1 || corrupted[0] != "signed" {
+		t.Errorf("Expected the corruption of signed to be reported, got: %v", corrupted)
+	}
+
+	// a damaged envelope header is reported too, rather than read as a plain miss
+	conn := signed.(*RedisStore).pool.Get()
+	if _, err := conn.Do("SET", signed.(*RedisStore).KeyWithPrefix("damaged"), "\x00gce not an envelope", ctx); err != nil {
+		t.Errorf("Error setting a raw value: %s", err)
+	}
+	conn.Close()
+	if err := signed.Get(ctx, "damaged", &value); err != ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss for a damaged entry, got: %v", err)
+	}
+	if len(corrupted) != 2 || corrupted[1] != "damaged" {
+		t.Errorf("Expected the corruption of damaged to be reported, got: %v", corrupted)
+	}
+
+	// integers are kept bare and unsigned so that they can be incremented
+	if err := signed.Set(ctx, "int", 10, DEFAULT); err != nil {
+		t.Errorf("Error setting int: %s", err)
+	}
+	if newValue, err := signed.Increment(ctx, "int", 5); err != nil || newValue != 15 {
+		t.Errorf("Expected 15, was %d, %v", newValue, err)
+	}
+}
+
+func TestRedisCache_Tags(t *testing.T) {
+	testTags(t, newRedisStore, func(t *testing.T, cache TaggedStore, tag string) int {
+		c := cache.(*RedisStore)
+		conn := c.pool.Get()
+		defer conn.Close()
+		n, err := redis.Int(conn.Do("SCARD", c.tagKey(tag)))
+		if err != nil {
+			t.Errorf("Error counting tag members: %s", err)
+		}
+		return n
+	})
+}
+
+func TestRedisCache_TagExpiry(t *testing.T) {
+	ctx := context.TODO()
+	c := newRedisStore(t, time.Hour).(*RedisStore)
+	conn := c.pool.Get()
+	defer conn.Close()
+	tagTTL := func(tag string) time.Duration {
+		ttl, err := redis.Int64(conn.Do("PTTL", c.tagKey(tag)))
+		if err != nil {
+			t.Errorf("Error reading the ttl of tag %s: %s", tag, err)
+		}
+		return time.Duration(ttl) * time.Millisecond
+	}
+	if _, err := conn.Do("DEL", c.tagKey("expiring"), c.tagKey("persisted")); err != nil {
+		t.Errorf("Error deleting the tags: %s", err)
+	}
+
+	// a new index set expires with its item, and is extended by longer lived ones only
+	if err := c.SetWithTags(ctx, "short", "value", 10*time.Second, "expiring"); err != nil {
+		t.Errorf("Error setting short: %s", err)
+	}
+	if ttl := tagTTL("expiring"); ttl <= 0 || ttl > 10*time.Second {
+		t.Errorf("Expected the tag to expire within 10s, ttl was %s", ttl)
+	}
+	if err := c.SetWithTags(ctx, "long", "value", time.Minute, "expiring"); err != nil {
+		t.Errorf("Error setting long: %s", err)
+	}
+	if err := c.SetWithTags(ctx, "shorter", "value", time.Second, "expiring"); err != nil {
+		t.Errorf("Error setting shorter: %s", err)
+	}
+	if ttl := tagTTL("expiring"); ttl <= 10*time.Second || ttl > time.Minute {
+		t.Errorf("Expected the tag to expire within a minute, ttl was %s", ttl)
+	}
+
+	// an item living forever keeps its index set forever
+	if err := c.SetWithTags(ctx, "forever", "value", FOREVER, "persisted"); err != nil {
+		t.Errorf("Error setting forever: %s", err)
+	}
+	if err := c.SetWithTags(ctx, "short", "value", 10*time.Second, "persisted"); err != nil {
+		t.Errorf("Error setting short: %s", err)
+	}
+	if ttl := tagTTL("persisted"); ttl != -time.Millisecond {
+		t.Errorf("Expected the tag to live forever, ttl was %s", ttl)
+	}
+
+	// an item named like an index set doesn't clobber it
+	if err := c.Set(ctx, "tag:expiring", "value", DEFAULT); err != nil {
+		t.Errorf("Error setting tag:expiring: %s", err)
+	}
+	if n, err := redis.Int(conn.Do("SCARD", c.tagKey("expiring"))); err != nil || n != 3 {
+		t.Errorf("Expected 3 members in the tag, got %d, %v", n, err)
+	}
+}
+
+func TestRedisCache_ScanAndDeleteByPattern(t *testing.T) {
+	ctx := context.TODO()
+	pool := dialRedisStore(t, time.Hour).(*RedisStore).pool
+	cache := NewRedisCache(pool, time.Hour, "scan*")
+	other := NewRedisCache(pool, time.Hour, "other")
+
+	for i := 0; i < 1200; i++ {
+		_ = cache.Set(ctx, fmt.Sprintf("user:%d", i), "u", DEFAULT)
+	}
+	_ = cache.Set(ctx, "product:1", "p", DEFAULT)
+	_ = other.Set(ctx, "user:1", "u", DEFAULT)
+
+	it := cache.Scan(ctx, "user:*")
+	seen := map[string]bool{}
+	for it.Next() {
+		seen[it.Key()] = true
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if len(seen) != 1200 || !seen["user:42"] {
+		t.Errorf("Expected 1200 user keys without prefix, got %d", len(seen))
+	}
+
+	deleted, err := cache.DeleteByPattern(ctx, "user:*")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if deleted != 1200 {
+		t.Errorf("Expected 1200 deleted keys, was %d", deleted)
+	}
+
+	var value string
+	if err = cache.Get(ctx, "product:1", &value); err != nil {
+		t.Errorf("Expected keys out of the pattern to be kept, got: %s", err)
+	}
+	if err = other.Get(ctx, "user:1", &value); err != nil {
+		t.Errorf("Expected keys of another prefix to be kept, got: %s", err)
+	}
+}
@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"context"
+	"time"
+)
+
+// Typed wraps a CacheStore so that values are read and written as T
+type Typed[T any] struct {
+	store CacheStore
+}
+
+// NewTyped returns a Typed backed by the passed store
+func NewTyped[T any](store CacheStore) *Typed[T] {
+	return &Typed[T]{store: store}
+}
+
+// Get retrieves the value of key, ErrCacheMiss is returned if the key is not found
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	if err := t.store.Get(ctx, key, &value); err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
+
+// Set sets the value of key, replacing any existing item
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, expire time.Duration) error {
+	return t.store.Set(ctx, key, value, expire)
+}
+
+// GetOrLoad retrieves the value of key, on a cache miss the value is loaded by loader and stored.
+// Errors of loader are returned as is and nothing is stored.
+func (t *Typed[T]) GetOrLoad(ctx context.Context, key string, expire time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	value, err := t.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if err != ErrCacheMiss {
+		return value, err
+	}
+
+	value, err = loader(ctx)
+	if err != nil {
+		return value, err
+	}
+	_ = t.store.Set(ctx, key, value, expire)
+	return value, nil
+}
@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type typedValue struct {
+	ID   int
+	Name string
+}
+
+func TestTyped_GetSet(t *testing.T) {
+	ctx := context.TODO()
+	typed := NewTyped[typedValue](newRedisStore(t, time.Hour))
+	_ = typed.store.Delete(ctx, "typed")
+
+	if _, err := typed.Get(ctx, "typed"); err != ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss, got: %v", err)
+	}
+
+	if err := typed.Set(ctx, "typed", typedValue{ID: 1, Name: "foo"}, DEFAULT); err != nil {
+		t.Errorf("Error setting a value: %s", err)
+	}
+	value, err := typed.Get(ctx, "typed")
+	if err != nil {
+		t.Errorf("Error getting a value: %s", err)
+	}
+	if value.ID != 1 || value.Name != "foo" {
+		t.Errorf("Expected to get {1 foo} back, got %v", value)
+	}
+}
+
+func TestTyped_GetOrLoad(t *testing.T) {
+	ctx := context.TODO()
+	typed := NewTyped[*typedValue](newRedisStore(t, time.Hour))
+	_ = typed.store.Delete(ctx, "typed_load")
+
+	loads := 0
+	loader := func(ctx context.Context) (*typedValue, error) {
+		loads++
+		return &typedValue{ID: loads}, nil
+	}
+	for i := 0; i < 2; i++ {
+		value, err := typed.GetOrLoad(ctx, "typed_load", DEFAULT, loader)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		if value.ID != 1 {
+			t.Errorf("Expected the loaded value to be cached, got %v", value)
+		}
+	}
+
+	errLoad := errors.New("load failed")
+	_, err := typed.GetOrLoad(ctx, "typed_load_err", DEFAULT, func(ctx context.Context) (*typedValue, error) {
+		return nil, errLoad
+	})
+	if err != errLoad {
+		t.Errorf("Expected the loader error, got: %v", err)
+	}
+	if _, err = typed.Get(ctx, "typed_load_err"); err != ErrCacheMiss {
+		t.Errorf("Expected load errors not to be cached, got: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errRangeNotSatisfiable means none of the requested ranges overlaps the body
+var errRangeNotSatisfiable = errors.New("cache: range not satisfiable")
+
+// byteRange a range of the body, end excluded
+type byteRange struct {
+	start, end int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.end-1, size)
+}
+
+// parseRange parses a Range header for a body of size bytes, see RFC 9110 section 14.2.
+// Ranges that can't be parsed are ignored by returning nil, errRangeNotSatisfiable is returned
+// when no range overlaps the body.
+func parseRange(header string, size int64) ([]byteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, nil
+	}
+
+	var ranges []byteRange
+	overlaps := false
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, nil
+		}
+		first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+		var r byteRange
+		if first == "" {
+			// suffix range, the last n bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, nil
+			}
+			if n == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{start: size - n, end: size}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+			end := size
+			if last != "" {
+				lastByte, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || lastByte < start {
+					return nil, nil
+				}
+				if lastByte < size {
+					end = lastByte + 1
+				}
+			}
+			if start >= size {
+				continue
+			}
+			r = byteRange{start: start, end: end}
+		}
+		overlaps = true
+		ranges = append(ranges, r)
+	}
+
+	if !overlaps {
+		return nil, errRangeNotSatisfiable
+	}
+
+	// like net/http, serve the whole body rather than ranges adding up to more than it
+	var total int64
+	for _, r := range ranges {
+		total += r.end - r.start
+	}
+	if total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches evaluates the If-Range precondition against the validators of the cached response,
+// an ETag must match with the strong comparison and a date must be the exact Last-Modified
+func ifRangeMatches(req *http.Request, respCache *ResponseCache) bool {
+	ifRange := strings.TrimSpace(req.Header.Get("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		etag := respCache.Header.Get("ETag")
+		return etag != "" && !strings.HasPrefix(etag, "W/") && etag == ifRange
+	}
+	date, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(respCache.Header.Get("Last-Modified"))
+	return err == nil && lastModified.Equal(date)
+}
+
+// replyWithRange answers the Range header of the request from the cached body,
+// it returns false when the whole body should be sent instead
+func replyWithRange(c *gin.Context, cfg *Config, respCache *ResponseCache) bool {
+	rangeHeader := c.Request.Header.Get("Range")
+	if rangeHeader == "" || c.Request.Method != http.MethodGet || respCache.Status != http.StatusOK {
+		return false
+	}
+	if !ifRangeMatches(c.Request, respCache) {
+		return false
+	}
+
+	size := int64(len(respCache.Data))
+	ranges, err := parseRange(rangeHeader, size)
+	if err == errRangeNotSatisfiable {
+		c.Writer.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		c.AbortWithStatus(http.StatusRequestedRangeNotSatisfiable)
+		return true
+	}
+	if len(ranges) == 0 {
+		return false
+	}
+
+	writeCachedHeader(c, cfg, respCache)
+	header := c.Writer.Header()
+
+	body := respCache.Data
+	if len(ranges) == 1 {
+		r := ranges[0]
+		header.Set("Content-Range", r.contentRange(size))
+		body = respCache.Data[r.start:r.end]
+	} else {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		contentType := respCache.Header.Get("Content-Type")
+		for _, r := range ranges {
+			partHeader := textproto.MIMEHeader{}
+			if contentType != "" {
+				partHeader.Set("Content-Type", contentType)
+			}
+			partHeader.Set("Content-Range", r.contentRange(size))
+			part, _ := w.CreatePart(partHeader)
+			_, _ = part.Write(respCache.Data[r.start:r.end])
+		}
+		_ = w.Close()
+		header.Set("Content-Type", "multipart/byteranges; boundary="+w.Boundary())
+		body = b.Bytes()
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	c.Writer.WriteHeader(http.StatusPartialContent)
+	if _, err := c.Writer.Write(body); err != nil {
+		cfg.logger.Errorf("write response error: %s", err)
+	}
+	c.Abort()
+	return true
+}
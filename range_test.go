@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		header string
+		ranges []byteRange
+		err    error
+	}{
+		{"bytes=0-4", []byteRange{{0, 5}}, nil},
+		{"bytes=5-", []byteRange{{5, 10}}, nil},
+		{"bytes=-3", []byteRange{{7, 10}}, nil},
+		{"bytes=-20", []byteRange{{0, 10}}, nil},
+		{"bytes=8-20", []byteRange{{8, 10}}, nil},
+		{"bytes=0-1, 4-5", []byteRange{{0, 2}, {4, 6}}, nil},
+		{"bytes=0-1, 20-30", []byteRange{{0, 2}}, nil},
+		{"bytes=10-20", nil, errRangeNotSatisfiable},
+		{"bytes=-0", nil, errRangeNotSatisfiable},
+		// ignored
+		{"bytes=5-2", nil, nil},
+		{"bytes=abc", nil, nil},
+		{"items=0-1", nil, nil},
+		{"bytes=0-9, 0-9", nil, nil},
+	}
+	for _, tc := range cases {
+		ranges, err := parseRange(tc.header, 10)
+		assert.Equal(t, tc.err, err, tc.header)
+		assert.Equal(t, tc.ranges, ranges, tc.header)
+	}
+}
+
+func TestCacheRanges(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("range:%d:", time.Now().UnixNano())),
+		WithETag(),
+		WithRanges(),
+	))
+	engine.GET("/range", func(c *gin.Context) {
+		c.String(http.StatusOK, "0123456789")
+	})
+
+	request := func(header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/range", nil)
+		req.Header = header
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	request(http.Header{})
+	w := request(http.Header{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	etag := w.Header().Get("ETag")
+
+	w = request(http.Header{"Range": {"bytes=2-4"}})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "234", w.Body.String())
+	assert.Equal(t, "bytes 2-4/10", w.Header().Get("Content-Range"))
+	assert.Equal(t, "3", w.Header().Get("Content-Length"))
+
+	w = request(http.Header{"Range": {"bytes=0-1,-2"}})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Range")+":"+string(body))
+	}
+	assert.Equal(t, []string{"bytes 0-1/10:01", "bytes 8-9/10:89"}, parts)
+
+	w = request(http.Header{"Range": {"bytes=20-"}})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Equal(t, "bytes */10", w.Header().Get("Content-Range"))
+
+	w = request(http.Header{"Range": {"bytes=2-4"}, "If-Range": {etag}})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+
+	w = request(http.Header{"Range": {"bytes=2-4"}, "If-Range": {`"outdated"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "0123"))
+}
@@ -0,0 +1,320 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+)
+
+// Rule configures the caching of the requests it matches, every condition that is set must hold.
+type Rule struct {
+	// Methods matched, GET and HEAD when empty, or any method for a bypass rule
+	Methods []string
+
+	// Route is either the gin route pattern, like /user/:id, or a glob matched against the request path,
+	// where * matches within a path segment and ** across segments. Any route when empty.
+	Route string
+
+	// Headers the request must carry, with the given value unless it is empty
+	Headers map[string]string
+
+	// Query parameters the request must carry, with the given value unless it is empty
+	Query map[string]string
+
+	// Bypass never caches the matched requests
+	Bypass bool
+
+	// TTL if zero, use the default expire instead
+	TTL time.Duration
+
+	// Store if nil, use the default cache store instead
+	Store persistence.CacheStore
+
+	// KeyTemplate builds the cache key from placeholders like {method}, {host}, {path}, {route}, {uri},
+	// {param:id}, {query:uid}, {header:Accept-Language}, {cookie:session} and {context:tenant}.
+	// The request uri with sorted query parameters is used when empty, preceded by the method unless it is GET,
+	// and followed by the hash of the body for the requests that have one, which are not cached if it is over 1MB.
+	KeyTemplate string
+}
+
+// Rules an ordered list of rules, a request is configured by the first rule it matches
+type Rules struct {
+	rules []*compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	methods map[string]bool
+	glob    *regexp.Regexp
+	key     []keyPart
+}
+
+// keyPart a literal part of a key template, or a placeholder read from the request
+type keyPart struct {
+	literal string
+	value   func(c *gin.Context) string
+}
+
+// NewRules validates and compiles the rules, first match wins
+func NewRules(rules ...Rule) (*Rules, error) {
+	r := &Rules{}
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("cache: rule %d: %w", i, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	if rule.TTL < 0 {
+		return nil, fmt.Errorf("negative ttl %s", rule.TTL)
+	}
+	if rule.Bypass && (rule.TTL > 0 || rule.Store != nil || rule.KeyTemplate != "") {
+		return nil, fmt.Errorf("bypass rule can't set ttl, store or key template")
+	}
+
+	compiled := &compiledRule{Rule: rule}
+	methods := rule.Methods
+	if len(methods) == 0 && !rule.Bypass {
+		// requests changing the state of the server are only cached when asked for
+		methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if len(methods) > 0 {
+		compiled.methods = map[string]bool{}
+		for _, method := range methods {
+			compiled.methods[strings.ToUpper(method)] = true
+		}
+	}
+	if rule.Route != "" {
+		if !strings.HasPrefix(rule.Route, "/") {
+			return nil, fmt.Errorf("route %q must start with /", rule.Route)
+		}
+		compiled.glob = compileGlob(rule.Route)
+	}
+	if len(rule.Headers) > 0 {
+		compiled.Headers = map[string]string{}
+		for name, value := range rule.Headers {
+			compiled.Headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	key, err := parseKeyTemplate(rule.KeyTemplate)
+	if err != nil {
+		return nil, err
+	}
+	compiled.key = key
+	return compiled, nil
+}
+
+// compileGlob turns a path glob into a regexp, * matches within a segment and ** across segments
+func compileGlob(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteByte('$')
+	return regexp.MustCompile(b.String())
+}
+
+// parseKeyTemplate splits the template into literals and placeholders
+func parseKeyTemplate(template string) ([]keyPart, error) {
+	var parts []keyPart
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			parts = append(parts, keyPart{literal: template})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, keyPart{literal: template[:start]})
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in key template %q", template)
+		}
+		value, err := keyPlaceholder(template[start+1 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, keyPart{value: value})
+		template = template[start+end+1:]
+	}
+	return parts, nil
+}
+
+func keyPlaceholder(placeholder string) (func(c *gin.Context) string, error) {
+	kind, name, hasName := strings.Cut(placeholder, ":")
+	if !hasName {
+		switch kind {
+		case "method":
+			return func(c *gin.Context) string { return c.Request.Method }, nil
+		case "host":
+			return func(c *gin.Context) string { return strings.ToLower(c.Request.Host) }, nil
+		case "path":
+			return func(c *gin.Context) string { return c.Request.URL.Path }, nil
+		case "route":
+			return func(c *gin.Context) string { return c.FullPath() }, nil
+		case "uri":
+			return sortedRequestURI, nil
+		}
+		return nil, fmt.Errorf("unknown key placeholder {%s}", placeholder)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("key placeholder {%s} without name", placeholder)
+	}
+
+	// values are escaped, so they can't forge the separators of the template
+	switch kind {
+	case "param":
+		return func(c *gin.Context) string { return url.QueryEscape(c.Param(name)) }, nil
+	case "query":
+		return func(c *gin.Context) string { return url.QueryEscape(c.Query(name)) }, nil
+	case "header":
+		return func(c *gin.Context) string { return url.QueryEscape(c.GetHeader(name)) }, nil
+	case "cookie":
+		return func(c *gin.Context) string {
+			value, _ := c.Cookie(name)
+			return url.QueryEscape(value)
+		}, nil
+	case "context":
+		return func(c *gin.Context) string {
+			value, ok := c.Get(name)
+			if !ok {
+				return ""
+			}
+			return url.QueryEscape(fmt.Sprint(value))
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown key placeholder {%s}", placeholder)
+}
+
+func (r *compiledRule) match(c *gin.Context) bool {
+	if r.methods != nil && !r.methods[c.Request.Method] {
+		return false
+	}
+	if r.Route != "" && r.Route != c.FullPath() && !r.glob.MatchString(c.Request.URL.Path) {
+		return false
+	}
+	for name, value := range r.Headers {
+		values, ok := c.Request.Header[name]
+		if !ok || (value != "" && !containsString(values, value)) {
+			return false
+		}
+	}
+	if len(r.Query) > 0 {
+		query := c.Request.URL.Query()
+		for name, value := range r.Query {
+			values, ok := query[name]
+			if !ok || (value != "" && !containsString(values, value)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *compiledRule) cacheKey(c *gin.Context) (string, error) {
+	if len(r.key) == 0 {
+		return defaultRuleKey(c)
+	}
+	var b strings.Builder
+	for _, part := range r.key {
+		if part.value != nil {
+			b.WriteString(part.value(c))
+		} else {
+			b.WriteString(part.literal)
+		}
+	}
+	return b.String(), nil
+}
+
+// defaultRuleKey returns the sorted request uri, with the method and the hash of the body if there is one,
+// so that a HEAD or requests with different payloads don't fill the entry of a GET
+func defaultRuleKey(c *gin.Context) (string, error) {
+	key := sortedRequestURI(c)
+	if c.Request.Method != http.MethodGet {
+		key = c.Request.Method + " " + key
+	}
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return key, nil
+	}
+	bodyHash, err := hashRequestBody(c.Request, defaultMaxBodySize, false)
+	if err != nil {
+		return "", err
+	}
+	return key + "#body:" + bodyHash, nil
+}
+
+// sortedRequestURI returns the request uri with sorted query parameters
+func sortedRequestURI(c *gin.Context) string {
+	uri, err := getRequestUriIgnoreQueryOrder(c.Request.RequestURI)
+	if err != nil {
+		return c.Request.RequestURI
+	}
+	return uri
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the first rule matching the request
+func (r *Rules) Match(c *gin.Context) (*Rule, bool) {
+	for _, rule := range r.rules {
+		if rule.match(c) {
+			return &rule.Rule, true
+		}
+	}
+	return nil, false
+}
+
+// Strategy returns a GetCacheStrategyByRequest configuring each request by its first matching rule,
+// requests matching no rule or a bypass rule are not cached
+func (r *Rules) Strategy() GetCacheStrategyByRequest {
+	return func(c *gin.Context) (bool, Strategy) {
+		for _, rule := range r.rules {
+			if !rule.match(c) {
+				continue
+			}
+			if rule.Bypass {
+				return false, Strategy{}
+			}
+			cacheKey, err := rule.cacheKey(c)
+			if err != nil {
+				// too large or unreadable bodies are not cached
+				return false, Strategy{}
+			}
+			return true, Strategy{
+				CacheKey:      cacheKey,
+				CacheStore:    rule.Store,
+				CacheDuration: rule.TTL,
+			}
+		}
+		return false, Strategy{}
+	}
+}
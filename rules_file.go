@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// RulesConfig the file form of the rules, in YAML or JSON:
+//
+//	rules:
+//	  - route: /user/:id
+//	    headers: {Authorization: ""}
+//	    bypass: true
+//	  - methods: [GET]
+//	    route: /user/:id
+//	    ttl: 5m
+//	    key: [route, param:id, query:lang]
+//	  - route: /static/**
+//	    ttl: 24h
+type RulesConfig struct {
+	Rules []RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig the file form of a Rule
+type RuleConfig struct {
+	Methods []string          `yaml:"methods"`
+	Route   string            `yaml:"route"`
+	Headers map[string]string `yaml:"headers"`
+	Query   map[string]string `yaml:"query"`
+	Bypass  bool              `yaml:"bypass"`
+	// TTL a duration like 30s or 5m
+	TTL string `yaml:"ttl"`
+	// Key the placeholders of the key template, joined with :, like [method, path, query:uid]
+	Key []string `yaml:"key"`
+	// KeyTemplate see Rule.KeyTemplate, can't be set with Key
+	KeyTemplate string `yaml:"key_template"`
+}
+
+// ParseRules parses and validates rules written in YAML or JSON, unknown fields are rejected
+func ParseRules(data []byte) (*Rules, error) {
+	cfg := RulesConfig{}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	rules := make([]Rule, 0, len(cfg.Rules))
+	for i, ruleCfg := range cfg.Rules {
+		rule, err := ruleCfg.rule()
+		if err != nil {
+			return nil, fmt.Errorf("cache: rule %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return NewRules(rules...)
+}
+
+func (r RuleConfig) rule() (Rule, error) {
+	rule := Rule{
+		Methods:     r.Methods,
+		Route:       r.Route,
+		Headers:     r.Headers,
+		Query:       r.Query,
+		Bypass:      r.Bypass,
+		KeyTemplate: r.KeyTemplate,
+	}
+	if r.TTL != "" {
+		ttl, err := time.ParseDuration(r.TTL)
+		if err != nil {
+			return Rule{}, err
+		}
+		rule.TTL = ttl
+	}
+	if len(r.Key) > 0 {
+		if r.KeyTemplate != "" {
+			return Rule{}, fmt.Errorf("key and key_template can't be both set")
+		}
+		components := make([]string, 0, len(r.Key))
+		for _, component := range r.Key {
+			if strings.ContainsAny(component, "{}") {
+				return Rule{}, fmt.Errorf("invalid key component %q", component)
+			}
+			components = append(components, "{"+component+"}")
+		}
+		rule.KeyTemplate = strings.Join(components, ":")
+	}
+	return rule, nil
+}
+
+// LoadRules reads the rules of a YAML or JSON file
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
+
+// RulesFile keeps the rules of a file up to date, the file is checked for changes every interval.
+// A change is applied at once to the requests that follow, a file that fails to load is rejected
+// and the previous rules stay active.
+type RulesFile struct {
+	path   string
+	logger Logger
+
+	rules atomic.Value // *Rules
+
+	// mu serializes reloads, modTime and size identify the last version loaded
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// WatchRulesFile loads the rules of the file and reloads them when it changes,
+// no watching is done if interval isn't positive. Rejected reloads are logged with logger.
+func WatchRulesFile(path string, interval time.Duration, logger Logger) (*RulesFile, error) {
+	if logger == nil {
+		logger = Discard{}
+	}
+	f := &RulesFile{path: path, logger: logger, stop: make(chan struct{})}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go f.watch(interval)
+	}
+	return f, nil
+}
+
+// Reload loads the file again, the current rules are kept if it fails
+func (f *RulesFile) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	return f.load(info)
+}
+
+func (f *RulesFile) load(info os.FileInfo) error {
+	// remember the version even if it is rejected, so that it is reported once
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	rules, err := LoadRules(f.path)
+	if err != nil {
+		return err
+	}
+	f.rules.Store(rules)
+	return nil
+}
+
+func (f *RulesFile) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.reloadIfChanged()
+		}
+	}
+}
+
+func (f *RulesFile) reloadIfChanged() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		f.logger.Errorf("stat rules file %s error: %s", f.path, err)
+		return
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return
+	}
+	if err := f.load(info); err != nil {
+		f.logger.Errorf("reload rules file %s error, keeping the previous rules: %s", f.path, err)
+	}
+}
+
+// Rules returns the rules currently active
+func (f *RulesFile) Rules() *Rules {
+	return f.rules.Load().(*Rules)
+}
+
+// Strategy returns a GetCacheStrategyByRequest using the rules active when the request comes in
+func (f *RulesFile) Strategy() GetCacheStrategyByRequest {
+	return func(c *gin.Context) (bool, Strategy) {
+		return f.Rules().Strategy()(c)
+	}
+}
+
+// Close stops watching the file, the last rules stay usable
+func (f *RulesFile) Close() {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+	})
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRulesYAML = `
+rules:
+  - route: /user/:id
+    headers: {Authorization: ""}
+    bypass: true
+  - methods: [GET]
+    route: /user/:id
+    ttl: 5m
+    key: [route, param:id, query:lang]
+  - route: /static/**
+    ttl: 24h
+`
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(testRulesYAML))
+	require.NoError(t, err)
+
+	shouldCache, strategy := ruleStrategy(rules, http.MethodGet, "/user/1?lang=en", nil)
+	assert.True(t, shouldCache)
+	assert.Equal(t, "/user/:id:1:en", strategy.CacheKey)
+	assert.Equal(t, 5*time.Minute, strategy.CacheDuration)
+
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/user/1", http.Header{"Authorization": {"Bearer x"}})
+	assert.False(t, shouldCache)
+
+	shouldCache, strategy = ruleStrategy(rules, http.MethodGet, "/static/js/main.js", nil)
+	assert.True(t, shouldCache)
+	assert.Equal(t, 24*time.Hour, strategy.CacheDuration)
+
+	// JSON is read as well
+	rules, err = ParseRules([]byte(`{"rules": [{"route": "/user/:id", "ttl": "1m", "key_template": "user:{param:id}"}]}`))
+	require.NoError(t, err)
+	_, strategy = ruleStrategy(rules, http.MethodGet, "/user/1", nil)
+	assert.Equal(t, "user:1", strategy.CacheKey)
+	assert.Equal(t, time.Minute, strategy.CacheDuration)
+}
+
+func TestParseRulesValidation(t *testing.T) {
+	for _, data := range []string{
+		"rules: [",
+		"rules:\n  - route: /a\n    tll: 1m\n",
+		"rules:\n  - route: /a\n    ttl: soon\n",
+		"rules:\n  - route: /a\n    ttl: -1m\n",
+		"rules:\n  - route: a\n",
+		"rules:\n  - route: /a\n    bypass: true\n    ttl: 1m\n",
+		"rules:\n  - route: /a\n    key: [method]\n    key_template: '{path}'\n",
+		"rules:\n  - route: /a\n    key: [methods]\n",
+		"rules:\n  - route: /a\n    key: ['{path}']\n",
+	} {
+		_, err := ParseRules([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestWatchRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testRulesYAML), 0o600))
+
+	f, err := WatchRulesFile(path, 10*time.Millisecond, nil)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, s := ruleStrategy(f.Rules(), http.MethodGet, "/user/1", nil)
+	assert.Equal(t, 5*time.Minute, s.CacheDuration)
+
+	// a valid change is picked up
+	writeRules(t, path, "rules:\n  - route: /user/:id\n    ttl: 1m\n", time.Now().Add(time.Second))
+	assert.Eventually(t, func() bool {
+		_, s := ruleStrategy(f.Rules(), http.MethodGet, "/user/1", nil)
+		return s.CacheDuration == time.Minute
+	}, time.Second, 10*time.Millisecond)
+
+	// an invalid change is rejected and the previous rules stay active
+	previous := f.Rules()
+	writeRules(t, path, "rules:\n  - route: /user/:id\n    ttl: soon\n", time.Now().Add(2*time.Second))
+	time.Sleep(50 * time.Millisecond)
+	assert.Same(t, previous, f.Rules())
+	assert.Error(t, f.Reload())
+	assert.Same(t, previous, f.Rules())
+
+	// invalid files are rejected on start
+	_, err = WatchRulesFile(path, 0, nil)
+	assert.Error(t, err)
+	_, err = WatchRulesFile(filepath.Join(t.TempDir(), "missing.yaml"), 0, nil)
+	assert.Error(t, err)
+}
+
+// writeRules writes the rules file with a modification time, so that changes are seen
+// even if the file system time resolution is coarse
+func writeRules(t *testing.T, path string, data string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ruleStrategy runs the strategy of the rules on a request routed by an engine with /user/:id and /static/*filepath
+func ruleStrategy(rules *Rules, method string, url string, header http.Header) (bool, Strategy) {
+	var shouldCache bool
+	var strategy Strategy
+	handler := func(c *gin.Context) {
+		c.Set("tenant", "acme")
+		shouldCache, strategy = rules.Strategy()(c)
+	}
+
+	engine := gin.New()
+	engine.Handle(method, "/user/:id", handler)
+	engine.Handle(method, "/static/*filepath", handler)
+	req := httptest.NewRequest(method, url, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	return shouldCache, strategy
+}
+
+func TestRulesFirstMatch(t *testing.T) {
+	rules, err := NewRules(
+		Rule{Route: "/user/:id", Headers: map[string]string{"authorization": ""}, Bypass: true},
+		Rule{Route: "/user/:id", Query: map[string]string{"fresh": "1"}, Bypass: true},
+		Rule{Methods: []string{"get"}, Route: "/user/:id", TTL: time.Minute, KeyTemplate: "user:{param:id}:{query:lang}"},
+		Rule{Route: "/user/:id", TTL: time.Hour},
+		Rule{Route: "/static/**/*.css", TTL: 24 * time.Hour},
+		Rule{Route: "/static/**", Bypass: true},
+	)
+	require.NoError(t, err)
+
+	shouldCache, strategy := ruleStrategy(rules, http.MethodGet, "/user/1?lang=en", nil)
+	assert.True(t, shouldCache)
+	assert.Equal(t, "user:1:en", strategy.CacheKey)
+	assert.Equal(t, time.Minute, strategy.CacheDuration)
+
+	// the earlier bypass rules win over the later ones
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/user/1", http.Header{"Authorization": {"Bearer x"}})
+	assert.False(t, shouldCache)
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/user/1?fresh=1", nil)
+	assert.False(t, shouldCache)
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/user/1?fresh=0", nil)
+	assert.True(t, shouldCache)
+
+	// other methods fall through to the next rule, keyed by the sorted uri
+	shouldCache, strategy = ruleStrategy(rules, http.MethodHead, "/user/1?b=2&a=1", nil)
+	assert.True(t, shouldCache)
+	assert.Equal(t, "HEAD /user/1?a=1&b=2", strategy.CacheKey)
+	assert.Equal(t, time.Hour, strategy.CacheDuration)
+
+	// rules without methods only cache GET and HEAD
+	shouldCache, _ = ruleStrategy(rules, http.MethodPost, "/user/1?b=2&a=1", nil)
+	assert.False(t, shouldCache)
+
+	shouldCache, strategy = ruleStrategy(rules, http.MethodGet, "/static/css/site/main.css", nil)
+	assert.True(t, shouldCache)
+	assert.Equal(t, 24*time.Hour, strategy.CacheDuration)
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/static/js/main.js", nil)
+	assert.False(t, shouldCache)
+
+	// no rule matches
+	rules, err = NewRules(Rule{Route: "/static/*.css"})
+	require.NoError(t, err)
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/user/1", nil)
+	assert.False(t, shouldCache)
+	shouldCache, _ = ruleStrategy(rules, http.MethodGet, "/static/css/main.css", nil)
+	assert.False(t, shouldCache)
+}
+
+func TestRulesDefaultKeyBody(t *testing.T) {
+	rules, err := NewRules(
+		Rule{Route: "/user/:id", Bypass: true, Headers: map[string]string{"Authorization": ""}},
+		Rule{Methods: []string{"POST"}, Route: "/user/:id"},
+	)
+	require.NoError(t, err)
+
+	var shouldCache bool
+	var strategy Strategy
+	var body string
+	engine := gin.New()
+	engine.POST("/user/:id", func(c *gin.Context) {
+		shouldCache, strategy = rules.Strategy()(c)
+		data, _ := c.GetRawData()
+		body = string(data)
+	})
+	request := func(payload string) (bool, Strategy) {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/1?b=2&a=1", strings.NewReader(payload)))
+		return shouldCache, strategy
+	}
+
+	shouldCache, strategy1 := request(`{"name":"a"}`)
+	assert.True(t, shouldCache)
+	assert.True(t, strings.HasPrefix(strategy1.CacheKey, "POST /user/1?a=1&b=2#body:"))
+	assert.Equal(t, `{"name":"a"}`, body)
+
+	// different payloads don't share an entry
+	_, strategy2 := request(`{"name":"b"}`)
+	assert.NotEqual(t, strategy1.CacheKey, strategy2.CacheKey)
+	_, strategy3 := request(`{"name":"a"}`)
+	assert.Equal(t, strategy1.CacheKey, strategy3.CacheKey)
+
+	// bodies over the limit are not cached
+	shouldCache, _ = request(strings.Repeat("a", defaultMaxBodySize+1))
+	assert.False(t, shouldCache)
+	assert.Len(t, body, defaultMaxBodySize+1)
+
+	// bypass rules without methods match any method
+	req := httptest.NewRequest(http.MethodPost, "/user/1", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer x")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, shouldCache)
+}
+
+func TestRulesHeadDoesNotFillGet(t *testing.T) {
+	rules, err := NewRules(Rule{Route: "/page"})
+	require.NoError(t, err)
+
+	engine := gin.New()
+	engine.Use(Cache(newRedisStore(time.Minute), time.Minute,
+		WithPrefixKey(fmt.Sprintf("rules_head:%d:", time.Now().UnixNano())),
+		WithCacheStrategyByRequest(rules.Strategy()),
+	))
+	engine.HEAD("/page", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	engine.GET("/page", func(c *gin.Context) {
+		c.String(http.StatusOK, "page")
+	})
+
+	request := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, "/page", nil))
+		return w
+	}
+
+	request(http.MethodHead)
+	assert.Equal(t, "page", request(http.MethodGet).Body.String())
+	assert.Equal(t, "page", request(http.MethodGet).Body.String())
+}
+
+func TestRulesKeyTemplate(t *testing.T) {
+	rules, err := NewRules(Rule{KeyTemplate: "{method} {host} {path} {route} {uri} {header:x-lang} {cookie:session} {context:tenant} {query:q}"})
+	require.NoError(t, err)
+
+	_, strategy := ruleStrategy(rules, http.MethodGet, "/user/1?q=a+b&a=1", http.Header{"X-Lang": {"en"}, "Cookie": {"session=s1"}})
+	assert.Equal(t, "GET example.com /user/1 /user/:id /user/1?a=1&q=a b en s1 acme a+b", strategy.CacheKey)
+
+	// values are escaped
+	rules, err = NewRules(Rule{KeyTemplate: "{query:a}:{query:b}"})
+	require.NoError(t, err)
+	_, strategy = ruleStrategy(rules, http.MethodGet, "/user/1?a=x%3Ay", nil)
+	assert.Equal(t, "x%3Ay:", strategy.CacheKey)
+}
+
+func TestNewRulesValidation(t *testing.T) {
+	for _, rule := range []Rule{
+		{TTL: -time.Second},
+		{Bypass: true, TTL: time.Second},
+		{Route: "user/:id"},
+		{KeyTemplate: "{unknown}"},
+		{KeyTemplate: "{query:}"},
+		{KeyTemplate: "user:{param:id"},
+	} {
+		_, err := NewRules(Rule{}, rule)
+		assert.Error(t, err, fmt.Sprintf("%+v", rule))
+	}
+}
+
+func TestCacheWithRules(t *testing.T) {
+	rules, err := NewRules(
+		Rule{Route: "/cache", Query: map[string]string{"uid": "nocache"}, Bypass: true},
+		Rule{Route: "/cache", KeyTemplate: "rules:{query:uid}"},
+	)
+	require.NoError(t, err)
+	cacheMiddleware := Cache(newRedisStore(1*time.Minute), 3*time.Second, WithCacheStrategyByRequest(rules.Strategy()))
+
+	w1 := mockHttpRequest(cacheMiddleware, "/cache?uid=u1", true)
+	w2 := mockHttpRequest(cacheMiddleware, "/cache?uid=u1&other=1", true)
+	assert.Equal(t, w1.Body, w2.Body)
+
+	w3 := mockHttpRequest(cacheMiddleware, "/cache?uid=nocache", true)
+	w4 := mockHttpRequest(cacheMiddleware, "/cache?uid=nocache", true)
+	assert.NotEqual(t, w3.Body, w4.Body)
+}
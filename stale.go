@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revalidateTimeout bounds the background revalidation like the backend timeout of a request
+const revalidateTimeout = 40 * time.Second
+
+type revalidateContextKey struct{}
+
+// isRevalidateRequest reports whether the request was issued by revalidateInBackground
+func isRevalidateRequest(req *http.Request) bool {
+	revalidate, _ := req.Context().Value(revalidateContextKey{}).(bool)
+	return revalidate
+}
+
+// revalidateInBackground replays a clone of the request through cfg.revalidateHandler with a
+// detached context, so that the stale entry of cacheKey is refreshed. At most one revalidation
+// runs per key.
+func revalidateInBackground(c *gin.Context, cfg *Config, revalidating *sync.Map, cacheKey string) {
+	if _, loaded := revalidating.LoadOrStore(cacheKey, struct{}{}); loaded {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), revalidateTimeout)
+	ctx = context.WithValue(ctx, revalidateContextKey{}, true)
+	req := c.Request.Clone(ctx)
+
+	// the body can't be shared with the original request, bodies over the size limit aren't revalidated
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		bodyBytes, err := readRequestBody(c.Request, cfg.maxBodySize)
+		if err != nil {
+			cancel()
+			revalidating.Delete(cacheKey)
+			cfg.logger.Errorf("read request body error: %s", err)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	}
+
+	go func() {
+		defer revalidating.Delete(cacheKey)
+		defer cancel()
+		defer func() {
+			if err := recover(); err != nil {
+				cfg.logger.Errorf("revalidate %s panic: %v", cacheKey, err)
+			}
+		}()
+
+		cfg.revalidateHandler.ServeHTTP(&discardResponseWriter{header: http.Header{}}, req)
+	}()
+}
+
+// discardResponseWriter the response of a background revalidation is only stored, never sent
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {
+}
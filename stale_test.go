@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	var calls int32
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, 1*time.Second,
+		WithPrefixKey(fmt.Sprintf("swr:%d:", time.Now().UnixNano())),
+		WithStaleWhileRevalidate(time.Minute, engine),
+	))
+	engine.GET("/swr", func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		c.String(http.StatusOK, "call:%d", atomic.AddInt32(&calls, 1))
+	})
+
+	request := func() string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/swr", nil))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "call:1", request())
+	assert.Equal(t, "call:1", request())
+
+	time.Sleep(1200 * time.Millisecond)
+
+	// stale, served at once while a single revalidation runs in background
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, "call:1", request())
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, "call:2", request())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRevalidateBodyLimit(t *testing.T) {
+	var calls int32
+	cfg := newConfigByOpts(WithMaxBodySize(8), WithStaleWhileRevalidate(time.Minute, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	})))
+	revalidating := &sync.Map{}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/swr", strings.NewReader(strings.Repeat("x", 16)))
+	revalidateInBackground(c, cfg, revalidating, "large")
+
+	// the large body isn't revalidated but stays whole for the handler
+	body, _ := io.ReadAll(c.Request.Body)
+	assert.Equal(t, strings.Repeat("x", 16), string(body))
+	_, running := revalidating.Load("large")
+	assert.False(t, running)
+
+	c.Request = httptest.NewRequest(http.MethodPost, "/swr", strings.NewReader("small"))
+	revalidateInBackground(c, cfg, revalidating, "small")
+	body, _ = io.ReadAll(c.Request.Body)
+	assert.Equal(t, "small", string(body))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStaleIfError(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	var calls int32
+	var failing int32
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, 1*time.Second,
+		WithPrefixKey(fmt.Sprintf("sie:%d:", time.Now().UnixNano())),
+		WithStaleIfError(time.Minute),
+	))
+	engine.GET("/sie", func(c *gin.Context) {
+		call := atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			c.Header("X-Failed", "true")
+			c.String(http.StatusBadGateway, "failed")
+			return
+		}
+		c.String(http.StatusOK, "call:%d", call)
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sie", nil))
+		return w
+	}
+
+	assert.Equal(t, "call:1", request().Body.String())
+	time.Sleep(1200 * time.Millisecond)
+
+	atomic.StoreInt32(&failing, 1)
+	w := request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "call:1", w.Body.String())
+	assert.Equal(t, "STALE", w.Header().Get("X-Cache"))
+	assert.NotEmpty(t, w.Header().Get("Warning"))
+	assert.Empty(t, w.Header().Get("X-Failed"))
+
+	atomic.StoreInt32(&failing, 0)
+	w = request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "call:3", w.Body.String())
+	assert.Empty(t, w.Header().Get("X-Cache"))
+}
+
+func TestStaleIfErrorTimeout(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	var calls int32
+	release := make(chan struct{})
+	var slow int32
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Header("X-Request-Id", "req-1")
+	})
+	engine.Use(CacheByRequestURI(memoryStore, 1*time.Second,
+		WithPrefixKey(fmt.Sprintf("siet:%d:", time.Now().UnixNano())),
+		WithStaleIfError(time.Minute),
+		WithBackendTimeout(100*time.Millisecond),
+	))
+	engine.GET("/siet", func(c *gin.Context) {
+		call := atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&slow) == 1 {
+			c.Header("X-Failed", "true")
+			<-release
+		}
+		c.String(http.StatusOK, "call:%d", call)
+	})
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	request := func() *http.Response {
+		resp, err := http.Get(server.URL + "/siet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readBody := func(resp *http.Response) string {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	assert.Equal(t, "call:1", readBody(request()))
+	time.Sleep(1200 * time.Millisecond)
+
+	// the stale entry is sent while the backend is still blocked
+	atomic.StoreInt32(&slow, 1)
+	resp := request()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "call:1", readBody(resp))
+	assert.Equal(t, "STALE", resp.Header.Get("X-Cache"))
+	assert.NotEmpty(t, resp.Header.Get("Warning"))
+	assert.Equal(t, "req-1", resp.Header.Get("X-Request-Id"))
+	assert.Empty(t, resp.Header.Get("X-Failed"))
+
+	// the late response of the backend is still stored
+	atomic.StoreInt32(&slow, 0)
+	close(release)
+	assert.Eventually(t, func() bool {
+		return readBody(request()) == "call:2"
+	}, time.Second, 20*time.Millisecond)
+}
+
+func TestBackendTimeoutSharedLoad(t *testing.T) {
+	middlewares := map[string]func(handler gin.HandlerFunc, opts ...Option) (gin.HandlerFunc, gin.HandlerFunc){
+		"Cache": func(handler gin.HandlerFunc, opts ...Option) (gin.HandlerFunc, gin.HandlerFunc) {
+			return CacheByRequestURI(newRedisStore(time.Minute), time.Minute, opts...), handler
+		},
+		"CachePage": func(handler gin.HandlerFunc, opts ...Option) (gin.HandlerFunc, gin.HandlerFunc) {
+			return func(c *gin.Context) {}, CachePage(newRedisStore(time.Minute), time.Minute, handler, opts...)
+		},
+	}
+
+	for name, middleware := range middlewares {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			release := make(chan struct{})
+			handler := func(c *gin.Context) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				c.String(http.StatusOK, "loaded")
+			}
+
+			engine := gin.New()
+			use, handle := middleware(handler,
+				WithPrefixKey(fmt.Sprintf("timeout:%s:%d:", name, time.Now().UnixNano())),
+				WithBackendTimeout(100*time.Millisecond),
+			)
+			engine.Use(use)
+			engine.GET("/timeout", handle)
+
+			server := httptest.NewServer(engine)
+			defer server.Close()
+			client := &http.Client{Timeout: 2 * time.Second}
+
+			request := func() (int, string, error) {
+				resp, err := client.Get(server.URL + "/timeout")
+				if err != nil {
+					return 0, "", err
+				}
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				return resp.StatusCode, string(body), err
+			}
+
+			// the first request runs the load, the others join it, every one of them times out
+			type result struct {
+				status int
+				err    error
+			}
+			results := make(chan result, 3)
+			go func() {
+				status, _, err := request()
+				results <- result{status, err}
+			}()
+			assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 5*time.Millisecond)
+			for i := 0; i < 2; i++ {
+				go func() {
+					status, _, err := request()
+					results <- result{status, err}
+				}()
+			}
+			for i := 0; i < 3; i++ {
+				r := <-results
+				assert.NoError(t, r.err)
+				assert.Equal(t, http.StatusInternalServerError, r.status)
+			}
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+			// the late response is still stored
+			close(release)
+			assert.Eventually(t, func() bool {
+				status, body, err := request()
+				return err == nil && status == http.StatusOK && body == "loaded"
+			}, time.Second, 20*time.Millisecond)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		})
+	}
+}
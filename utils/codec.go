@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Codec describes how values are encoded into the bytes kept by a cache store
+type Codec interface {
+	// Marshal encodes the passed value
+	Marshal(value interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into the passed ptr
+	Unmarshal(data []byte, ptr interface{}) error
+
+	// ID identifies the codec inside stored envelopes, it must be unique and stable
+	ID() byte
+}
+
+var (
+	// GobCodec encodes values with encoding/gob, it is the default codec
+	GobCodec Codec = gobCodec{}
+
+	// JSONCodec encodes values with encoding/json
+	JSONCodec Codec = jsonCodec{}
+
+	// MsgpackCodec encodes values with MessagePack
+	MsgpackCodec Codec = msgpackCodec{}
+)
+
+var codecs = map[byte]Codec{}
+
+func init() {
+	RegisterCodec(GobCodec)
+	RegisterCodec(JSONCodec)
+	RegisterCodec(MsgpackCodec)
+}
+
+// RegisterCodec makes a codec available to decode envelopes written with its ID.
+// It is not safe for concurrent use and should be called from an init function.
+func RegisterCodec(c Codec) {
+	codecs[c.ID()] = c
+}
+
+// CodecByID returns the registered codec with the given ID
+func CodecByID(id byte) (Codec, bool) {
+	c, ok := codecs[id]
+	return c, ok
+}
+
+type gobCodec struct{}
+
+func (gobCodec) ID() byte { return 1 }
+
+func (gobCodec) Marshal(value interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := gob.NewEncoder(&b)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, ptr interface{}) error {
+	decoder := gob.NewDecoder(bytes.NewBuffer(data))
+	return decoder.Decode(ptr)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte { return 2 }
+
+func (jsonCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return json.Unmarshal(data, ptr)
+}
+
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ID() byte { return 3 }
+
+func (msgpackCodec) Marshal(value interface{}) ([]byte, error) {
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, msgpackHandle).Encode(value); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, ptr interface{}) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(ptr)
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// EnvelopeVersion is the format version written by this package
+const EnvelopeVersion byte = 1
+
+// envelopeMagic starts every envelope, the leading byte is never produced by the integer fast path
+var envelopeMagic = []byte{0x9c, 'g', 'c', 'e'}
+
+// magic(4) + version(1) + codec(1) + createdAt(8) + expiresAt(8)
+const envelopeHeaderLen = 22
+
+var (
+	// ErrUnknownEnvelope means the data is not an envelope, or has a version or codec this build can't read
+	ErrUnknownEnvelope = errors.New("cache: unknown envelope format")
+
+	// ErrEnvelopeExpired means the envelope's expiry has passed
+	ErrEnvelopeExpired = errors.New("cache: envelope expired")
+)
+
+// Envelope wraps every stored value so that readers can tell how it was written
+type Envelope struct {
+	Version   byte
+	CodecID   byte
+	CreatedAt time.Time
+	// ExpiresAt zero means the value never expires
+	ExpiresAt time.Time
+	Payload   []byte
+}
+
+// MarshalBinary returns the wire form of the envelope
+func (e *Envelope) MarshalBinary() ([]byte, error) {
+	b := make([]byte, envelopeHeaderLen, envelopeHeaderLen+len(e.Payload))
+	copy(b, envelopeMagic)
+	b[4] = e.Version
+	b[5] = e.CodecID
+	binary.BigEndian.PutUint64(b[6:14], uint64(unixNano(e.CreatedAt)))
+	binary.BigEndian.PutUint64(b[14:22], uint64(unixNano(e.ExpiresAt)))
+	return append(b, e.Payload...), nil
+}
+
+// UnmarshalBinary parses the wire form of the envelope,
+// ErrUnknownEnvelope is returned for data written in another format or version
+func (e *Envelope) UnmarshalBinary(data []byte) error {
+	if !IsEnvelope(data) || data[4] != EnvelopeVersion {
+		return ErrUnknownEnvelope
+	}
+	e.Version = data[4]
+	e.CodecID = data[5]
+	e.CreatedAt = fromUnixNano(int64(binary.BigEndian.Uint64(data[6:14])))
+	e.ExpiresAt = fromUnixNano(int64(binary.BigEndian.Uint64(data[14:22])))
+	e.Payload = data[envelopeHeaderLen:]
+	return nil
+}
+
+// IsEnvelope reports whether data starts with an envelope header
+func IsEnvelope(data []byte) bool {
+	return len(data) >= envelopeHeaderLen && bytes.Equal(data[:len(envelopeMagic)], envelopeMagic)
+}
+
+// Seal serializes the value with the codec and wraps it into an envelope.
+// Integers are left bare so that stores can still increment and decrement them.
+func Seal(c Codec, value interface{}, expire time.Duration) ([]byte, error) {
+	if isInteger(reflect.ValueOf(value)) {
+		return SerializeWithCodec(c, value)
+	}
+
+	payload, err := SerializeWithCodec(c, value)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	e := &Envelope{Version: EnvelopeVersion, CodecID: c.ID(), CreatedAt: now, Payload: payload}
+	if expire > 0 {
+		e.ExpiresAt = now.Add(expire)
+	}
+	return e.MarshalBinary()
+}
+
+// Open unwraps data written by Seal into the passed ptr, the payload is decoded
+// with the codec recorded in the envelope.
+func Open(data []byte, ptr interface{}) error {
+	if !IsEnvelope(data) {
+		// bare integers, anything else is written by an older version
+		if IsIntegerPtr(ptr) {
+			return DeserializeWithCodec(GobCodec, data, ptr)
+		}
+		return ErrUnknownEnvelope
+	}
+
+	e := &Envelope{}
+	if err := e.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt) {
+		return ErrEnvelopeExpired
+	}
+	c, ok := CodecByID(e.CodecID)
+	if !ok {
+		return ErrUnknownEnvelope
+	}
+	return DeserializeWithCodec(c, e.Payload, ptr)
+}
+
+// IsIntegerPtr reports whether ptr points to an integer, which is stored bare by Seal
+func IsIntegerPtr(ptr interface{}) bool {
+	v := reflect.ValueOf(ptr)
+	return v.Kind() == reflect.Ptr && isInteger(v.Elem())
+}
+
+func isInteger(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type envelopeValue struct {
+	Name string
+	Data []byte
+}
+
+func TestSealOpen(t *testing.T) {
+	for _, c := range []Codec{GobCodec, JSONCodec, MsgpackCodec} {
+		data, err := Seal(c, &envelopeValue{Name: "foo", Data: []byte("bar")}, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, IsEnvelope(data))
+
+		e := &Envelope{}
+		require.NoError(t, e.UnmarshalBinary(data))
+		assert.Equal(t, c.ID(), e.CodecID)
+		assert.Equal(t, time.Minute, e.ExpiresAt.Sub(e.CreatedAt))
+
+		v := &envelopeValue{}
+		require.NoError(t, Open(data, v))
+		assert.Equal(t, "foo", v.Name)
+		assert.Equal(t, []byte("bar"), v.Data)
+	}
+}
+
+func TestSealKeepsIntegersBare(t *testing.T) {
+	data, err := Seal(GobCodec, 42, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "42", string(data))
+
+	var i int
+	require.NoError(t, Open(data, &i))
+	assert.Equal(t, 42, i)
+}
+
+func TestOpenUnknownEnvelope(t *testing.T) {
+	data, err := Seal(GobCodec, "foo", 0)
+	require.NoError(t, err)
+
+	var s string
+	future := append([]byte{}, data...)
+	future[4] = EnvelopeVersion + 1
+	assert.Equal(t, ErrUnknownEnvelope, Open(future, &s))
+
+	unknownCodec := append([]byte{}, data...)
+	unknownCodec[5] = 0xff
+	assert.Equal(t, ErrUnknownEnvelope, Open(unknownCodec, &s))
+
+	legacy, err := GobCodec.Marshal("foo")
+	require.NoError(t, err)
+	assert.Equal(t, ErrUnknownEnvelope, Open(legacy, &s))
+}
+
+func TestOpenExpiredEnvelope(t *testing.T) {
+	e := &Envelope{
+		Version:   EnvelopeVersion,
+		CodecID:   GobCodec.ID(),
+		CreatedAt: time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	data, err := e.MarshalBinary()
+	require.NoError(t, err)
+
+	var s string
+	assert.Equal(t, ErrEnvelopeExpired, Open(data, &s))
+}
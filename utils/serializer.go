@@ -1,14 +1,18 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/gob"
 	"reflect"
 	"strconv"
 )
 
 // Serialize returns a []byte representing the passed value
 func Serialize(value interface{}) ([]byte, error) {
+	return SerializeWithCodec(GobCodec, value)
+}
+
+// SerializeWithCodec returns a []byte representing the passed value, raw bytes and
+// integers are kept as is so that they can still be incremented by the store
+func SerializeWithCodec(c Codec, value interface{}) ([]byte, error) {
 	if bytes, ok := value.([]byte); ok {
 		return bytes, nil
 	}
@@ -20,16 +24,17 @@ func Serialize(value interface{}) ([]byte, error) {
 		return []byte(strconv.FormatUint(v.Uint(), 10)), nil
 	}
 
-	var b bytes.Buffer
-	encoder := gob.NewEncoder(&b)
-	if err := encoder.Encode(value); err != nil {
-		return nil, err
-	}
-	return b.Bytes(), nil
+	return c.Marshal(value)
 }
 
 // Deserialize deserialices the passed []byte into a the passed ptr interface{}
 func Deserialize(byt []byte, ptr interface{}) (err error) {
+	return DeserializeWithCodec(GobCodec, byt, ptr)
+}
+
+// DeserializeWithCodec deserialices the passed []byte into a the passed ptr interface{}
+// with the given codec, it is the counterpart of SerializeWithCodec
+func DeserializeWithCodec(c Codec, byt []byte, ptr interface{}) (err error) {
 	if bytes, ok := ptr.(*[]byte); ok {
 		*bytes = byt
 		return nil
@@ -59,10 +64,5 @@ func Deserialize(byt []byte, ptr interface{}) (err error) {
 		}
 	}
 
-	b := bytes.NewBuffer(byt)
-	decoder := gob.NewDecoder(b)
-	if err = decoder.Decode(ptr); err != nil {
-		return err
-	}
-	return nil
+	return c.Unmarshal(byt, ptr)
 }
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrSignatureMismatch means the stored data was not signed with the signer's secret
+var ErrSignatureMismatch = errors.New("cache: signature mismatch")
+
+// Signer appends and verifies an HMAC-SHA256 over the cache key and the stored data
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner returns a Signer using the passed secret
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// Sign returns data followed by its signature
+func (s *Signer) Sign(key string, data []byte) []byte {
+	signed := make([]byte, 0, len(data)+sha256.Size)
+	signed = append(signed, data...)
+	return append(signed, s.mac(key, data)...)
+}
+
+// Verify checks the signature appended by Sign and returns the data without it
+func (s *Signer) Verify(key string, signed []byte) ([]byte, error) {
+	if len(signed) < sha256.Size {
+		return nil, ErrSignatureMismatch
+	}
+	data, sum := signed[:len(signed)-sha256.Size], signed[len(signed)-sha256.Size:]
+	if !hmac.Equal(sum, s.mac(key, data)) {
+		return nil, ErrSignatureMismatch
+	}
+	return data, nil
+}
+
+func (s *Signer) mac(key string, data []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	// length prefix the key so that key and data can't be shifted into each other
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(key)))
+	h.Write(n[:])
+	h.Write([]byte(key))
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-contrib/cache/persistence"
+)
+
+// varyIndexKey the key of the Vary header names recorded for the entries of cacheKey
+func varyIndexKey(cacheKey string) string {
+	return cacheKey + "#vary"
+}
+
+// parseVary returns the canonical, sorted and deduplicated header names of the Vary header,
+// wildcard is true for Vary: *
+func parseVary(header http.Header) (names []string, wildcard bool) {
+	seen := map[string]bool{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				return nil, true
+			}
+			name = http.CanonicalHeaderKey(name)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, false
+}
+
+// responseVary returns the Vary header names that select a variant of the response.
+// Accept-Encoding doesn't when the body is negotiated from a single stored encoding.
+func responseVary(cfg *Config, header http.Header) ([]string, bool) {
+	names, wildcard := parseVary(header)
+	if cfg.contentEncoding == "" {
+		return names, wildcard
+	}
+	selecting := names[:0]
+	for _, name := range names {
+		if name != "Accept-Encoding" {
+			selecting = append(selecting, name)
+		}
+	}
+	return selecting, wildcard
+}
+
+// normalizeHeaderValue joins the values of a header, trimming the elements of comma separated lists
+// and collapsing inner whitespace so that equivalent requests select the same variant
+func normalizeHeaderValue(values []string) string {
+	var elements []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			element = strings.Join(strings.Fields(element), " ")
+			if element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return strings.Join(elements, ",")
+}
+
+// variantKey returns the key of the variant of cacheKey selected by the request headers named by the Vary header
+func variantKey(cacheKey string, names []string, reqHeader http.Header) string {
+	if len(names) == 0 {
+		return cacheKey
+	}
+	h := sha256.New()
+	var n [8]byte
+	for _, name := range names {
+		for _, s := range []string{name, normalizeHeaderValue(reqHeader.Values(name))} {
+			binary.BigEndian.PutUint64(n[:], uint64(len(s)))
+			h.Write(n[:])
+			h.Write([]byte(s))
+		}
+	}
+	return cacheKey + "#variant:" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// lookupVariantKey returns the key of the variant matching the request, according to the recorded Vary index
+func lookupVariantKey(ctx context.Context, cacheStore persistence.CacheStore, cacheKey string, reqHeader http.Header) string {
+	var names []string
+	if err := cacheStore.Get(ctx, varyIndexKey(cacheKey), &names); err != nil {
+		return cacheKey
+	}
+	return variantKey(cacheKey, names, reqHeader)
+}
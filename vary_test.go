@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVary(t *testing.T) {
+	names, wildcard := parseVary(http.Header{"Vary": {"accept-language, Accept-Encoding", "Accept-Language"}})
+	assert.False(t, wildcard)
+	assert.Equal(t, []string{"Accept-Encoding", "Accept-Language"}, names)
+
+	_, wildcard = parseVary(http.Header{"Vary": {"Accept-Language, *"}})
+	assert.True(t, wildcard)
+}
+
+func TestVariantKey(t *testing.T) {
+	names := []string{"Accept-Language"}
+	key := variantKey("/cache", names, http.Header{"Accept-Language": {"en-US, fr;q=0.8"}})
+	assert.Equal(t, key, variantKey("/cache", names, http.Header{"Accept-Language": {" en-US,fr;q=0.8 "}}))
+	assert.Equal(t, key, variantKey("/cache", names, http.Header{"Accept-Language": {"en-US", "fr;q=0.8"}}))
+	assert.NotEqual(t, key, variantKey("/cache", names, http.Header{"Accept-Language": {"fr"}}))
+	assert.NotEqual(t, key, variantKey("/cache", names, http.Header{}))
+	assert.Equal(t, "/cache", variantKey("/cache", nil, http.Header{"Accept-Language": {"fr"}}))
+}
+
+func TestCacheVary(t *testing.T) {
+	memoryStore := newRedisStore(1 * time.Minute)
+
+	engine := gin.New()
+	engine.Use(CacheByRequestURI(memoryStore, time.Minute,
+		WithPrefixKey(fmt.Sprintf("vary:%d:", time.Now().UnixNano())),
+		WithVary(),
+	))
+	engine.GET("/vary", func(c *gin.Context) {
+		c.Header("Vary", c.Query("vary"))
+		c.String(http.StatusOK, "%s:%d", c.GetHeader("Accept-Language"), rand.Int())
+	})
+
+	request := func(url string, language string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Accept-Language", language)
+		engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	en := request("/vary?vary=Accept-Language", "en")
+	fr := request("/vary?vary=Accept-Language", "fr")
+	assert.NotEqual(t, en, fr)
+	assert.Equal(t, en, request("/vary?vary=Accept-Language", "en"))
+	assert.Equal(t, fr, request("/vary?vary=Accept-Language", "fr"))
+
+	// Vary: * is never stored
+	uncached := request("/vary?vary=*", "en")
+	assert.NotEqual(t, uncached, request("/vary?vary=*", "en"))
+}